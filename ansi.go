@@ -2,6 +2,8 @@ package paintbrush
 
 import (
 	"image"
+	"io"
+	"log/slog"
 	"sync"
 )
 
@@ -19,6 +21,16 @@ type Canvas struct {
 	Threads             int               // Number of threads for parallel processing
 	ForbiddenCharacters map[rune]struct{} // Characters to exclude from rendering
 	Weights             map[rune]float64  // Custom weights for character selection
+	ColorMode           ColorMode         // Palette used for cell colors and escape codes
+	AutoColorMode       bool              // Detect the color mode from the environment when painting
+	RenderMode          RenderMode        // How each cell is fitted to the image
+	Dither              Dither            // How color error is spread between neighbouring cells
+	Metric              ColorMetric       // Color space in which color differences are measured
+	GammaCorrect        bool              // Average colors in linear light rather than sRGB
+	Resample            Resample          // Filter used to sample the image
+	Scorer              GlyphScorer       // Glyph scoring strategy, nil for ColorScorer
+	Logger              *slog.Logger      // Receives warnings such as missing glyphs, nil to discard them
+	Stream              io.Writer         // Receives rows of ANSI text as soon as they are painted, nil to disable
 
 	// Output Results
 	Result           string // Raw output string
@@ -29,8 +41,16 @@ type Canvas struct {
 	ResultRGBAHeight int    // Height of the RGBA output
 
 	// Internal State
-	Progress float32    // Current progress of rendering (0.0 to 1.0)
-	mu       sync.Mutex // Mutex for thread-safe operations
+	Progress   float32               // Current progress of rendering (0.0 to 1.0)
+	mu         sync.Mutex            // Mutex for thread-safe operations
+	onProgress func(done, total int) // Called after every rendered cell
+	fontData   []byte                // Font data the glyphs were rasterized from
+	fontParams fontParams            // Settings the glyphs were rasterized with
+	palette    palette               // Palette in effect for the current painting
+	pixels     *pixelBuffer          // Image converted for fast sampling
+	resampled  *pixelBuffer          // Image filtered to one pixel per sample, nil for nearest
+	blocks     map[rune]*Glyph       // Block elements rasterized for the render mode
+	candidates []glyphCandidate      // Glyphs eligible for selection, in rune order
 }
 
 // New creates and returns a new Canvas instance with default settings.
@@ -92,6 +112,91 @@ func (c *Canvas) GetResultRGBADimensions() (width, height int) {
 	return c.ResultRGBAWidth, c.ResultRGBAHeight
 }
 
+// SetColorMode sets the palette used for cell colors and escape codes.
+func (c *Canvas) SetColorMode(mode ColorMode) {
+	c.ColorMode = mode
+}
+
+// GetColorMode returns the current color mode.
+func (c *Canvas) GetColorMode() ColorMode {
+	return c.ColorMode
+}
+
+// SetAutoColorMode enables or disables detecting the color mode from the
+// environment when painting. While enabled, ColorMode is ignored.
+func (c *Canvas) SetAutoColorMode(auto bool) {
+	c.AutoColorMode = auto
+}
+
+// SetRenderMode sets how each cell is fitted to the image.
+func (c *Canvas) SetRenderMode(mode RenderMode) {
+	c.RenderMode = mode
+}
+
+// GetRenderMode returns the current render mode.
+func (c *Canvas) GetRenderMode() RenderMode {
+	return c.RenderMode
+}
+
+// SetDither sets how color error is spread between neighbouring cells.
+func (c *Canvas) SetDither(dither Dither) {
+	c.Dither = dither
+}
+
+// GetDither returns the current dithering method.
+func (c *Canvas) GetDither() Dither {
+	return c.Dither
+}
+
+// SetMetric sets the color space in which color differences are measured.
+func (c *Canvas) SetMetric(metric ColorMetric) {
+	c.Metric = metric
+}
+
+// GetMetric returns the current color metric.
+func (c *Canvas) GetMetric() ColorMetric {
+	return c.Metric
+}
+
+// SetGammaCorrect enables or disables averaging colors in linear light.
+func (c *Canvas) SetGammaCorrect(enabled bool) {
+	c.GammaCorrect = enabled
+}
+
+// SetResample sets the filter used to sample the image.
+func (c *Canvas) SetResample(resample Resample) {
+	c.Resample = resample
+}
+
+// GetResample returns the current resampling filter.
+func (c *Canvas) GetResample() Resample {
+	return c.Resample
+}
+
+// SetScorer sets the strategy used to score glyphs for each cell. A nil
+// scorer selects the default ColorScorer.
+func (c *Canvas) SetScorer(scorer GlyphScorer) {
+	c.Scorer = scorer
+}
+
+// GetScorer returns the current glyph scorer.
+func (c *Canvas) GetScorer() GlyphScorer {
+	return c.Scorer
+}
+
+// SetLogger sets the logger that receives warnings. A nil logger discards
+// them.
+func (c *Canvas) SetLogger(logger *slog.Logger) {
+	c.Logger = logger
+}
+
+// SetStream sets a writer that receives every row of the image as text with
+// ANSI escape codes as soon as it and the rows above it are painted. Rows are
+// then painted top to bottom. A nil writer disables streaming.
+func (c *Canvas) SetStream(w io.Writer) {
+	c.Stream = w
+}
+
 // AddForbiddenCharacter adds a character to the list of forbidden characters.
 func (c *Canvas) AddForbiddenCharacter(char rune) {
 	c.ForbiddenCharacters[char] = struct{}{}
@@ -122,7 +227,8 @@ func (c *Canvas) IsForbiddenCharacter(char rune) bool {
 	return forbidden
 }
 
-// SetAspectRatio sets the aspect ratio for the output.
+// SetAspectRatio sets the aspect ratio for the output. The font is
+// rasterized again before the next painting.
 func (c *Canvas) SetAspectRatio(ratio float64) {
 	c.AspectRatio = ratio
 }
@@ -132,7 +238,8 @@ func (c *Canvas) GetAspectRatio() float64 {
 	return c.AspectRatio
 }
 
-// SetGlyphDimensions sets the width and height of glyphs.
+// SetGlyphDimensions sets the width and height of glyphs. The font is
+// rasterized again before the next painting.
 func (c *Canvas) SetGlyphDimensions(width, height int) {
 	c.GlyphWidth = width
 	c.GlyphHeight = height
@@ -144,6 +251,7 @@ func (c *Canvas) GetGlyphDimensions() (width, height int) {
 }
 
 // SetRuneLimits sets the start and end rune limits for character selection.
+// The font is rasterized again before the next painting.
 func (c *Canvas) SetRuneLimits(start, end int) {
 	c.RuneStart = start
 	c.RuneLimit = end
@@ -159,6 +267,22 @@ func (c *Canvas) SetWeights(weights map[rune]float64) {
 	c.Weights = weights
 }
 
+// GetWeight returns the weight applied to a character during selection. A
+// weight in Weights takes precedence over the Weight of the font's glyph,
+// which is 1 unless changed in Font.Glyphs, so weights removed from Weights
+// no longer apply. The error of each glyph is divided by its weight exactly
+// once, and characters whose weight is 0 or less, or not finite, are never
+// chosen.
+func (c *Canvas) GetWeight(char rune) float64 {
+	if weight, exists := c.Weights[char]; exists {
+		return weight
+	}
+	if glyph, exists := c.Font.Glyphs[char]; exists {
+		return glyph.Weight
+	}
+	return 1
+}
+
 // AddWeights adds the provided weights to the existing weight map.
 // If a character already has a weight, it will be updated with the new value.
 func (c *Canvas) AddWeights(weights map[rune]float64) {
@@ -0,0 +1,273 @@
+package paintbrush
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Batch converts every image in a directory with the configuration of one
+// canvas, writing the results to a mirrored directory tree.
+type Batch struct {
+	Canvas    *Canvas     // Configuration every image is rendered with
+	Include   []string    // Patterns of files to convert, all files when empty
+	Exclude   []string    // Patterns of files to skip
+	Recursive bool        // Descend into subdirectories
+	Jobs      int         // Images converted concurrently, GOMAXPROCS when 0 or less
+	Format    Format      // Format the results are written in
+	Extension string      // Extension of output files, chosen by the format when empty
+	HTML      HTMLOptions // Options of HTML output
+	SVG       SVGOptions  // Options of SVG output
+}
+
+// BatchReport lists the outcome of a batch conversion. Paths are those of the
+// source files, sorted.
+type BatchReport struct {
+	Converted []string       // Files converted successfully
+	Failed    []BatchFailure // Files that could not be converted
+}
+
+// BatchFailure is a file that could not be converted, and why.
+type BatchFailure struct {
+	Path string
+	Err  error
+}
+
+// Error returns the path followed by the error, or only the error if it
+// already names the path, like the errors of opening or decoding the file.
+func (f BatchFailure) Error() string {
+	var pathErr *fs.PathError
+	if errors.As(f.Err, &pathErr) && pathErr.Path == f.Path {
+		return f.Err.Error()
+	}
+	return f.Path + ": " + f.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (f BatchFailure) Unwrap() error {
+	return f.Err
+}
+
+// Err returns the failures joined into one error, or nil if every file was
+// converted.
+func (r *BatchReport) Err() error {
+	errs := make([]error, len(r.Failed))
+	for i, failure := range r.Failed {
+		errs[i] = failure
+	}
+	return errors.Join(errs...)
+}
+
+// batchJob is a file to convert and where to write the result.
+type batchJob struct {
+	source, output string
+}
+
+// Run converts the files below src whose names match Include and none of
+// Exclude, and writes each result followed by a line break to the same
+// relative path below dst, with the file's extension replaced. Patterns use
+// the syntax of filepath.Match and are matched against the file name, or
+// against the slash-separated path relative to src when they contain a slash.
+//
+// The font is rasterized once, if the render mode needs it, and shared by
+// every image. Images are decoded with the formats registered with the image
+// package. Files that cannot be read, rendered or written are listed in the
+// report instead of stopping the batch, so the returned error is only set
+// when src cannot be walked, a pattern is invalid or ctx is cancelled. The
+// report then still lists the files handled so far.
+func (b *Batch) Run(ctx context.Context, src, dst string) (*BatchReport, error) {
+	for _, pattern := range append(append([]string{}, b.Include...), b.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: pattern %q", ErrInvalidOption, pattern)
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		return nil, err
+	}
+	if err := b.Canvas.prepareFont(); err != nil {
+		return nil, err
+	}
+
+	report := &BatchReport{}
+	var mu sync.Mutex
+	fail := func(path string, err error) {
+		mu.Lock()
+		report.Failed = append(report.Failed, BatchFailure{Path: path, Err: err})
+		mu.Unlock()
+	}
+
+	jobs := make(chan batchJob)
+	var wg sync.WaitGroup
+	workers := b.Jobs
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				err := b.convert(ctx, job)
+				if ctx.Err() != nil {
+					continue
+				}
+				if err != nil {
+					fail(job.source, err)
+					continue
+				}
+				mu.Lock()
+				report.Converted = append(report.Converted, job.source)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := b.walk(ctx, src, dst, jobs, fail)
+	close(jobs)
+	wg.Wait()
+
+	sort.Strings(report.Converted)
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Path < report.Failed[j].Path
+	})
+	return report, err
+}
+
+// walk sends a job for every matching file below src, reporting directories
+// that cannot be read and files whose output path is already taken.
+func (b *Batch) walk(ctx context.Context, src, dst string, jobs chan<- batchJob, fail func(string, error)) error {
+	extension := b.Extension
+	if extension == "" {
+		extension = b.Format.extension()
+	}
+	outputs := make(map[string]string)
+
+	// Skip the output tree when it lies inside the source tree
+	skip, _ := filepath.Abs(dst)
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == src {
+				return err
+			}
+			fail(path, err)
+			return nil
+		}
+		if d.IsDir() {
+			if path == src {
+				return nil
+			}
+			if abs, _ := filepath.Abs(path); !b.Recursive || abs == skip {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if !b.matches(rel) {
+			return nil
+		}
+
+		output := filepath.Join(dst, strings.TrimSuffix(rel, filepath.Ext(rel))+extension)
+		if other, taken := outputs[output]; taken {
+			fail(path, fmt.Errorf("output %s is already written for %s", output, other))
+			return nil
+		}
+		outputs[output] = path
+
+		select {
+		case jobs <- batchJob{source: path, output: output}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// matches reports whether a file, given by its path relative to the source
+// directory, is included in the batch.
+func (b *Batch) matches(rel string) bool {
+	included := len(b.Include) == 0
+	for _, pattern := range b.Include {
+		if matchPattern(pattern, rel) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range b.Exclude {
+		if matchPattern(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern matches a pattern against the file name, or against the whole
+// relative path if the pattern contains a slash.
+func matchPattern(pattern, rel string) bool {
+	name := filepath.ToSlash(rel)
+	if !strings.Contains(pattern, "/") {
+		name = filepath.Base(rel)
+	}
+	matched, _ := filepath.Match(pattern, name)
+	return matched
+}
+
+// convert renders one file and writes its result, removing the output again
+// if writing fails.
+func (b *Batch) convert(ctx context.Context, job batchJob) error {
+	img, err := decodeImageFile(job.source)
+	if err != nil {
+		return err
+	}
+	result, err := b.Canvas.Render(ctx, img)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(job.output), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(job.output)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	switch b.Format {
+	case FormatHTML:
+		opts := b.HTML
+		if opts.Title == "" {
+			opts.Title = filepath.Base(job.source)
+		}
+		_, err = result.WriteHTML(w, opts)
+	case FormatSVG:
+		_, err = result.WriteSVG(w, b.SVG)
+	default:
+		_, err = result.WriteFormat(w, b.Format)
+	}
+	if err == nil {
+		w.WriteByte('\n')
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(job.output)
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package paintbrush
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files below dir, writing a small PNG image for every
+// path ending in .png and the given contents otherwise.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contents == "" && strings.HasSuffix(name, ".png") {
+			err = png.Encode(file, img)
+		} else {
+			_, err = file.WriteString(contents)
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBatchRun(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(src, "out")
+	writeTree(t, src, map[string]string{
+		"a.png":          "",
+		"bad.png":        "not an image",
+		"notes.txt":      "not matched",
+		"skip.png":       "",
+		"sub/b.png":      "",
+		"sub/skip.png":   "",
+		"sub/deep/c.png": "",
+		"sub/deep/d.png": "",
+		"out/old.png":    "", // Inside the destination, which is never converted
+	})
+
+	canvas := New()
+	canvas.SetWidth(4)
+	batch := &Batch{
+		Canvas:    canvas,
+		Include:   []string{"*.png"},
+		Exclude:   []string{"skip.png", "sub/deep/d.png"},
+		Recursive: true,
+		Jobs:      2,
+	}
+	report, err := batch.Run(context.Background(), src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(src, filepath.FromSlash(name))
+		}
+		return paths
+	}
+	if want := join("a.png", "sub/b.png", "sub/deep/c.png"); !reflect.DeepEqual(report.Converted, want) {
+		t.Errorf("converted %v, want %v", report.Converted, want)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Path != join("bad.png")[0] {
+		t.Fatalf("failed %v, want only bad.png", report.Failed)
+	}
+	if msg := report.Failed[0].Error(); strings.Count(msg, "bad.png") != 1 {
+		t.Errorf("failure %q does not name the file exactly once", msg)
+	}
+	if report.Err() == nil {
+		t.Error("report without error despite a failure")
+	}
+
+	var outputs []string
+	filepath.WalkDir(dst, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dst, path)
+			outputs = append(outputs, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if want := []string{"a.ans", "old.png", "sub/b.ans", "sub/deep/c.ans"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("outputs %v, want %v", outputs, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "deep", "c.ans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "\n") || strings.Count(string(data), "\n") < 2 {
+		t.Errorf("output %q is not a rendering followed by a line break", data)
+	}
+}
+
+func TestBatchRunWithoutRecursion(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a.png": "", "sub/b.png": ""})
+
+	canvas := New()
+	canvas.SetWidth(4)
+	report, err := (&Batch{Canvas: canvas}).Run(context.Background(), src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(src, "a.png")}; !reflect.DeepEqual(report.Converted, want) || len(report.Failed) != 0 {
+		t.Errorf("converted %v and failed %v, want only %v", report.Converted, report.Failed, want)
+	}
+}
+
+func TestBatchRunRejectsBadPatterns(t *testing.T) {
+	batch := &Batch{Canvas: New(), Include: []string{"["}}
+	if _, err := batch.Run(context.Background(), t.TempDir(), t.TempDir()); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Run with pattern %q = %v, want ErrInvalidOption", "[", err)
+	}
+}
@@ -0,0 +1,252 @@
+package paintbrush
+
+import (
+	"fmt"
+	"math"
+)
+
+// RenderMode selects how each cell of the output is fitted to the image.
+type RenderMode int
+
+const (
+	RenderGlyphs     RenderMode = iota // Search the font for the best matching glyph
+	RenderHalfBlocks                   // Upper half blocks showing two vertical pixels per cell
+	RenderQuadrants                    // Quadrant block elements showing a 2x2 grid per cell
+	RenderSextants                     // Unicode 13 sextants showing a 2x3 grid per cell
+	RenderBraille                      // Braille patterns showing a 2x4 grid of dots per cell
+)
+
+// String returns the name of the render mode.
+func (m RenderMode) String() string {
+	switch m {
+	case RenderGlyphs:
+		return "glyphs"
+	case RenderHalfBlocks:
+		return "half"
+	case RenderQuadrants:
+		return "quadrants"
+	case RenderSextants:
+		return "sextants"
+	case RenderBraille:
+		return "braille"
+	}
+	return fmt.Sprintf("RenderMode(%d)", int(m))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (m RenderMode) MarshalText() ([]byte, error) {
+	return marshalEnum(m, RenderBraille)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (m *RenderMode) UnmarshalText(text []byte) error {
+	return unmarshalEnum(m, RenderBraille, text)
+}
+
+// blockLayout describes a grid of sub-cells and the rune displaying each
+// combination of lit sub-cells. Masks number the sub-cells in row-major order.
+type blockLayout struct {
+	cols, rows int
+	runes      []rune
+	dots       bool // Sub-cells are drawn as dots rather than filled
+}
+
+var blockLayouts = map[RenderMode]*blockLayout{
+	RenderHalfBlocks: {cols: 1, rows: 2, runes: []rune{' ', '▀', '▄', '█'}},
+	RenderQuadrants: {cols: 2, rows: 2, runes: []rune{
+		' ', '▘', '▝', '▀', '▖', '▌', '▞', '▛', '▗', '▚', '▐', '▜', '▄', '▙', '▟', '█',
+	}},
+	RenderSextants: {cols: 2, rows: 3, runes: sextantRunes()},
+	RenderBraille:  {cols: 2, rows: 4, runes: brailleRunes(), dots: true},
+}
+
+// sextantRunes returns the sextant block elements. The range starting at
+// U+1FB00 omits the four patterns already covered by older block elements.
+func sextantRunes() []rune {
+	runes := make([]rune, 64)
+	next := rune(0x1FB00)
+	for mask := range runes {
+		switch mask {
+		case 0:
+			runes[mask] = ' '
+		case 0b010101:
+			runes[mask] = '▌'
+		case 0b101010:
+			runes[mask] = '▐'
+		case 0b111111:
+			runes[mask] = '█'
+		default:
+			runes[mask] = next
+			next++
+		}
+	}
+	return runes
+}
+
+// brailleRunes returns the braille patterns, translating row-major masks to
+// the braille dot numbering where dots 1-3 and 4-6 run down each column and
+// dots 7 and 8 form the bottom row.
+func brailleRunes() []rune {
+	dotBits := [8]int{0, 3, 1, 4, 2, 5, 6, 7}
+	runes := make([]rune, 256)
+	for mask := range runes {
+		pattern := 0
+		for bit, dot := range dotBits {
+			if mask&(1<<bit) != 0 {
+				pattern |= 1 << dot
+			}
+		}
+		runes[mask] = rune(0x2800 + pattern)
+	}
+	return runes
+}
+
+// subCell returns the index of the sub-cell containing the glyph pixel.
+func (l *blockLayout) subCell(fontCharX, fontCharY, glyphWidth, glyphHeight int) int {
+	return (fontCharY*l.rows/glyphHeight)*l.cols + fontCharX*l.cols/glyphWidth
+}
+
+// buildBlockGlyphs rasterizes the block elements used by the render mode at
+// the font's glyph dimensions, so that they can be blitted like any glyph.
+func (c *Canvas) buildBlockGlyphs() {
+	layout := blockLayouts[c.RenderMode]
+	c.blocks = make(map[rune]*Glyph)
+	for mask, r := range layout.runes {
+		pixels := make([]uint8, c.Font.GlyphWidth*c.Font.GlyphHeight)
+		for y := 0; y < c.Font.GlyphHeight; y++ {
+			for x := 0; x < c.Font.GlyphWidth; x++ {
+				if mask&(1<<layout.subCell(x, y, c.Font.GlyphWidth, c.Font.GlyphHeight)) == 0 {
+					continue
+				}
+				if layout.dots && !isDotPixel(x, y, layout, c.Font.GlyphWidth, c.Font.GlyphHeight) {
+					continue
+				}
+				pixels[y*c.Font.GlyphWidth+x] = 255
+			}
+		}
+		c.blocks[r] = &Glyph{Unicode: int(r), UTF8: string(r), Pixels: pixels, Weight: 1, fit: newGlyphFit(pixels, c.Font.GlyphWidth)}
+	}
+}
+
+// isDotPixel reports whether the glyph pixel lies within the central half of
+// its sub-cell, where a braille dot is drawn.
+func isDotPixel(x, y int, layout *blockLayout, glyphWidth, glyphHeight int) bool {
+	cellX := (float64(x) + 0.5) * float64(layout.cols) / float64(glyphWidth)
+	cellY := (float64(y) + 0.5) * float64(layout.rows) / float64(glyphHeight)
+	fracX := cellX - math.Floor(cellX)
+	fracY := cellY - math.Floor(cellY)
+	return fracX >= 0.25 && fracX < 0.75 && fracY >= 0.25 && fracY < 0.75
+}
+
+// processBlockTask renders a cell with the block elements of the render mode.
+// The cell is averaged into sub-cells and every split of the sub-cells into a
+// foreground and background group is tried, keeping the split with the least
+// error once its colors are quantized to the palette.
+func (c *Canvas) processBlockTask(task Task, imgCharWidth, imgCharHeight float64) TaskResult {
+	layout := blockLayouts[c.RenderMode]
+	imgXBegin := float64(task.CharX) * imgCharWidth
+	imgYBegin := float64(task.CharY) * imgCharHeight
+
+	cells := layout.cols * layout.rows
+	colors := make([]Vec4, cells)
+	counts := make([]float64, cells)
+	for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
+		for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
+			imgX := imgXBegin + imgCharWidth*(float64(fontCharX)+0.5)/float64(c.Font.GlyphWidth)
+			imgY := imgYBegin + imgCharHeight*(float64(fontCharY)+0.5)/float64(c.Font.GlyphHeight)
+			cell := layout.subCell(fontCharX, fontCharY, c.Font.GlyphWidth, c.Font.GlyphHeight)
+			colors[cell] = colors[cell].Add(c.sampleImage(imgX, imgY, task.Bias))
+			counts[cell]++
+		}
+	}
+	for i := range colors {
+		if counts[i] > 0 {
+			colors[i] = colors[i].Div(counts[i])
+		}
+	}
+
+	var bestMask int
+	var bestFg, bestBg Vec4
+	if c.palette.mode == ColorModeMonochrome {
+		// Without colors each sub-cell is simply lit or unlit
+		threshold := 0.5
+		if c.GammaCorrect {
+			threshold = srgbChannelToLinear(threshold)
+		}
+		for i, col := range colors {
+			if col.A >= 0.2 && luminance(col) >= threshold {
+				bestMask |= 1 << i
+			}
+		}
+		bestFg = c.palette.quantize(Vec4{A: 1})
+	} else {
+		bestErr := math.MaxFloat64
+		for mask := 0; mask < len(layout.runes); mask++ {
+			var fgCol, bgCol Vec4
+			var fgSum, bgSum float64
+			for i, col := range colors {
+				if mask&(1<<i) != 0 {
+					fgCol = fgCol.Add(col.Mul(counts[i]))
+					fgSum += counts[i]
+				} else {
+					bgCol = bgCol.Add(col.Mul(counts[i]))
+					bgSum += counts[i]
+				}
+			}
+
+			if fgSum > 0 {
+				fgCol = fgCol.Div(fgSum)
+			}
+			fgCol.A = 1
+
+			if bgSum > 0 {
+				bgCol = bgCol.Div(bgSum)
+			}
+			if bgCol.A < 0.2 {
+				bgCol = Vec4{}
+			} else {
+				bgCol.A = 1
+			}
+
+			fgCol = c.quantize(fgCol)
+			bgCol = c.quantize(bgCol)
+
+			error := 0.0
+			for i, col := range colors {
+				x := bgCol
+				if mask&(1<<i) != 0 {
+					x = fgCol
+				}
+				error += c.distance(col, x) * counts[i]
+			}
+
+			if error < bestErr {
+				bestErr = error
+				bestMask = mask
+				bestFg = fgCol
+				bestBg = bgCol
+			}
+		}
+	}
+
+	if c.GammaCorrect {
+		bestFg, bestBg = bestFg.ToSRGB(), bestBg.ToSRGB()
+	}
+
+	glyph := c.blocks[layout.runes[bestMask]]
+
+	return TaskResult{
+		CharX: task.CharX,
+		CharY: task.CharY,
+		Fg:    bestFg,
+		Bg:    bestBg,
+		Glyph: glyph,
+	}
+}
+
+// luminance returns the relative luminance of the color.
+func luminance(v Vec4) float64 {
+	return 0.2126*v.R + 0.7152*v.G + 0.0722*v.B
+}
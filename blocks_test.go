@@ -0,0 +1,34 @@
+package paintbrush
+
+import (
+	"image"
+	"testing"
+)
+
+func TestBlockModesSkipFont(t *testing.T) {
+	for _, mode := range []RenderMode{RenderHalfBlocks, RenderQuadrants, RenderSextants, RenderBraille} {
+		canvas := New()
+		canvas.SetImage(image.NewRGBA(image.Rect(0, 0, 40, 40)))
+		canvas.SetWidth(5)
+		canvas.SetGlyphDimensions(8, 16)
+		canvas.SetRenderMode(mode)
+		if err := canvas.Paint(); err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if len(canvas.Font.Glyphs) != 0 {
+			t.Errorf("%v: rasterized %d glyphs", mode, len(canvas.Font.Glyphs))
+		}
+		if width, height := canvas.GetResultRGBADimensions(); width != 5*8 || height%16 != 0 {
+			t.Errorf("%v: RGBA output is %dx%d, want cells of 8x16", mode, width, height)
+		}
+
+		// The font is rasterized once glyphs are needed
+		canvas.SetRenderMode(RenderGlyphs)
+		if err := canvas.Paint(); err != nil {
+			t.Fatalf("%v then glyphs: %v", mode, err)
+		}
+		if len(canvas.Font.Glyphs) == 0 {
+			t.Errorf("%v then glyphs: no glyphs rasterized", mode)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	paintbrush "github.com/jordanella/go-ansi-paintbrush"
+)
+
+// runBatch executes the batch command and returns the exit code.
+func runBatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("paintbrush batch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	var s settings
+	s.register(fs)
+	include := fs.String("include", "*.png,*.jpg,*.jpeg,*.gif,*.bmp,*.tif,*.tiff,*.webp", "comma-separated `patterns` of files to convert")
+	exclude := fs.String("exclude", "", "comma-separated `patterns` of files to skip")
+	recursive := fs.Bool("r", false, "convert files in subdirectories too")
+	jobs := fs.Int("jobs", 0, "number of images converted at once (default the number of CPUs)")
+	extension := fs.String("ext", "", "`extension` of output files (default .ans, .h, .sh, .html or .svg by format)")
+	quiet := fs.Bool("q", false, "only report failures")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage(fs, stdout, "paintbrush batch [flags] source destination",
+				"Converts every image in the source directory and writes each result to\n"+
+					"the same relative path in the destination directory. Patterns are\n"+
+					"matched against file names, or against paths relative to the source\n"+
+					"directory when they contain a slash.")
+			return exitOK
+		}
+		return usageError(stderr, err)
+	}
+	if fs.NArg() != 2 {
+		return usageError(stderr, fmt.Errorf("batch expects a source and a destination directory, got %d arguments", fs.NArg()))
+	}
+
+	canvas, err := s.canvas(fs)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	batch := &paintbrush.Batch{
+		Canvas:    canvas,
+		Include:   splitList(*include),
+		Exclude:   splitList(*exclude),
+		Recursive: *recursive,
+		Jobs:      *jobs,
+		Format:    s.format,
+		HTML:      s.html,
+		SVG:       s.svg,
+		Extension: *extension,
+	}
+	report, err := batch.Run(context.Background(), fs.Arg(0), fs.Arg(1))
+	if errors.Is(err, paintbrush.ErrInvalidOption) {
+		return usageError(stderr, err)
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	for _, failure := range report.Failed {
+		fmt.Fprintf(stderr, "paintbrush: %s\n", message(failure))
+	}
+	if !*quiet || len(report.Failed) > 0 {
+		fmt.Fprintf(stderr, "%d converted, %d failed\n", len(report.Converted), len(report.Failed))
+	}
+	if len(report.Failed) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
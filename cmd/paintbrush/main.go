@@ -0,0 +1,202 @@
+// Command paintbrush converts images to text art with ANSI colors.
+//
+// Usage:
+//
+//	paintbrush [flags] [image]
+//	paintbrush batch [flags] source destination
+//	paintbrush watch [flags] image
+//
+// The image is read from the given path, or from standard input when the path
+// is "-" or missing. The result is written to standard output, or to the file
+// given with -o. The batch command converts every image in the source
+// directory to the same relative path in the destination directory. The watch
+// command redraws the terminal whenever the image, font file or preset file
+// changes. Run paintbrush -help, or -help after a command, for the list of
+// flags.
+//
+// The exit code is 0 on success, 1 when converting fails and 2 for invalid
+// usage. Errors are written to standard error, one line each.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	paintbrush "github.com/jordanella/go-ansi-paintbrush"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "batch" {
+		return runBatch(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "watch" {
+		return runWatch(args[1:], stdout, stderr)
+	}
+
+	fs := flag.NewFlagSet("paintbrush", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	var s settings
+	s.register(fs)
+	output := fs.String("o", "", "write the result to `file` instead of standard output")
+	stream := fs.Bool("stream", false, "write rows to standard output as soon as they are painted (ANSI format only)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage(fs, stdout, "paintbrush [flags] [image]",
+				"Converts an image, or standard input when the image is - or missing, to\n"+
+					"text art with ANSI colors. Run 'paintbrush batch -help' to convert\n"+
+					"directories, or 'paintbrush watch -help' to redraw on changes.")
+			return exitOK
+		}
+		return usageError(stderr, err)
+	}
+	if fs.NArg() > 1 {
+		return usageError(stderr, fmt.Errorf("expected at most one image, got %d", fs.NArg()))
+	}
+	if *stream && (*output != "" || s.format != paintbrush.FormatANSI) {
+		return usageError(stderr, errors.New("-stream cannot be combined with -o or -format"))
+	}
+
+	canvas, err := s.canvas(fs)
+	if err != nil {
+		return usageError(stderr, err)
+	}
+	img, err := readImage(fs.Arg(0), stdin)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	canvas.SetImage(img)
+	s.html.Title = title(fs.Arg(0))
+
+	if *stream {
+		canvas.SetStream(stdout)
+		if err := canvas.Paint(); err != nil {
+			return fail(stderr, err)
+		}
+		return exitOK
+	}
+
+	result, err := canvas.PaintContext(context.Background())
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := writeResult(result, &s, *output, stdout); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+// usage writes the help text of a command to w.
+func usage(fs *flag.FlagSet, w io.Writer, synopsis, description string) {
+	fmt.Fprintln(w, "Usage:", synopsis)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, description)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// readImage decodes the image at path, or from stdin when path is empty or
+// "-".
+func readImage(path string, stdin io.Reader) (image.Image, error) {
+	r := stdin
+	name := "standard input"
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r, name = file, path
+	}
+
+	img, _, err := image.Decode(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return img, nil
+}
+
+// title returns the title of HTML documents for the image at path.
+func title(path string) string {
+	if path == "" || path == "-" {
+		return "paintbrush"
+	}
+	return filepath.Base(path)
+}
+
+// writeResult writes the result in the output format to the file at path, or
+// to stdout when path is empty, followed by a line break.
+func writeResult(result *paintbrush.Result, s *settings, path string, stdout io.Writer) error {
+	if path == "" {
+		w := bufio.NewWriter(stdout)
+		if err := s.write(w, result); err != nil {
+			return err
+		}
+		w.WriteByte('\n')
+		return w.Flush()
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = s.write(w, result)
+	if err == nil {
+		w.WriteByte('\n')
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// usageError reports invalid usage and returns its exit code.
+func usageError(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "paintbrush: %s\n", message(err))
+	fmt.Fprintln(stderr, "Run 'paintbrush -help' for usage.")
+	return exitUsage
+}
+
+// fail reports an error and returns its exit code.
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "paintbrush: %s\n", message(err))
+	return exitError
+}
+
+// message returns the text of an error without the package's own prefix,
+// which the command adds itself.
+func message(err error) string {
+	return strings.ReplaceAll(err.Error(), "paintbrush: ", "")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	paintbrush "github.com/jordanella/go-ansi-paintbrush"
+)
+
+// settings holds the flags that configure a canvas.
+type settings struct {
+	preset    string
+	font      string
+	width     int
+	height    int
+	glyph     string
+	aspect    float64
+	runes     string
+	weights   string
+	forbid    string
+	threads   int
+	color     string
+	mode      paintbrush.RenderMode
+	dither    paintbrush.Dither
+	metric    paintbrush.ColorMetric
+	gamma     bool
+	resample  paintbrush.Resample
+	structure float64
+	format    paintbrush.Format
+	html      paintbrush.HTMLOptions
+	svg       paintbrush.SVGOptions
+}
+
+// register defines the flags on fs.
+func (s *settings) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.preset, "preset", "", "apply a built-in preset ("+strings.Join(paintbrush.BuiltinPresets(), ", ")+") or a preset `file` before the other flags")
+	fs.StringVar(&s.font, "font", "", "TrueType font `file` (default embedded Fira Mono)")
+	fs.IntVar(&s.width, "width", 0, "output width in characters (default 40 when no height is given)")
+	fs.IntVar(&s.height, "height", 0, "output height in characters")
+	fs.StringVar(&s.glyph, "glyph", "7x14", "glyph `size` in pixels, as WIDTHxHEIGHT")
+	fs.Float64Var(&s.aspect, "aspect", 1, "aspect ratio correction")
+	fs.StringVar(&s.runes, "runes", "32-95", "`range` of characters to use, as START-END with END excluded; decimal, 0x or U+ code points")
+	fs.StringVar(&s.weights, "weights", "", "character `weights`, as CHAR=WEIGHT pairs separated by commas, e.g. █=0.85,▀=0.9")
+	fs.StringVar(&s.forbid, "forbid", "", "`characters` never to use")
+	fs.IntVar(&s.threads, "threads", 4, "number of rendering workers")
+	fs.StringVar(&s.color, "color", "truecolor", "color `mode`: truecolor, 256, 16, monochrome or auto")
+	fs.TextVar(&s.mode, "mode", paintbrush.RenderGlyphs, "render `mode`: glyphs, half, quadrants, sextants or braille")
+	fs.TextVar(&s.dither, "dither", paintbrush.DitherNone, "dithering `method`: none, floyd-steinberg, atkinson, sierra or bayer")
+	fs.TextVar(&s.metric, "metric", paintbrush.MetricSRGB, "color `metric`: srgb, linear, cielab, ciede2000 or oklab")
+	fs.BoolVar(&s.gamma, "gamma", false, "average colors in linear light")
+	fs.TextVar(&s.resample, "resample", paintbrush.ResampleNearest, "resampling `filter`: nearest, box, bilinear, bicubic or lanczos")
+	fs.Float64Var(&s.structure, "structure", 0, "favour glyphs following edges, scaling structural dissimilarity (0 disables)")
+	fs.TextVar(&s.format, "format", paintbrush.FormatANSI, "output `format`: ansi, c, bash, html or svg")
+	fs.BoolVar(&s.html.Classes, "html-classes", false, "color HTML with CSS classes instead of inline styles")
+	fs.BoolVar(&s.html.Standalone, "html-standalone", false, "write a complete HTML document")
+	fs.StringVar(&s.html.FontFamily, "html-font", "monospace", "CSS font-family of HTML output")
+	fs.StringVar(&s.svg.FontFamily, "svg-font", "monospace", "font family of SVG output")
+}
+
+// canvas creates a canvas from the preset and the flags explicitly set on
+// fs, which override the preset.
+func (s *settings) canvas(fs *flag.FlagSet) (*paintbrush.Canvas, error) {
+	opts, err := s.options(fs)
+	if err != nil {
+		return nil, err
+	}
+	return paintbrush.NewWithOptions(opts...)
+}
+
+// options converts the preset and the flags explicitly set on fs into canvas
+// options.
+func (s *settings) options(fs *flag.FlagSet) ([]paintbrush.Option, error) {
+	var opts []paintbrush.Option
+	if s.preset != "" {
+		preset, err := loadPreset(s.preset)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, paintbrush.WithPreset(preset))
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		var opt paintbrush.Option
+		switch f.Name {
+		case "font":
+			opt = paintbrush.WithFontFile(s.font)
+		case "width", "height":
+			opt = paintbrush.WithSize(s.width, s.height)
+		case "glyph":
+			var width, height int
+			width, height, err = parseSize(s.glyph)
+			opt = paintbrush.WithGlyphDimensions(width, height)
+		case "aspect":
+			opt = paintbrush.WithAspectRatio(s.aspect)
+		case "runes":
+			var start, end int
+			start, end, err = parseRuneRange(s.runes)
+			opt = paintbrush.WithRuneLimits(start, end)
+		case "weights":
+			var weights map[rune]float64
+			weights, err = parseWeights(s.weights)
+			opt = paintbrush.WithWeights(weights)
+		case "forbid":
+			opt = paintbrush.WithForbiddenCharacters([]rune(s.forbid)...)
+		case "threads":
+			opt = paintbrush.WithThreads(s.threads)
+		case "color":
+			if s.color == "auto" {
+				opt = paintbrush.WithAutoColorMode()
+				break
+			}
+			var mode paintbrush.ColorMode
+			if err = mode.UnmarshalText([]byte(s.color)); err != nil {
+				err = fmt.Errorf("invalid value %q for flag -color", s.color)
+			}
+			opt = paintbrush.WithColorMode(mode)
+		case "mode":
+			opt = paintbrush.WithRenderMode(s.mode)
+		case "dither":
+			opt = paintbrush.WithDither(s.dither)
+		case "metric":
+			opt = paintbrush.WithMetric(s.metric)
+		case "gamma":
+			opt = paintbrush.WithGammaCorrect(s.gamma)
+		case "resample":
+			opt = paintbrush.WithResample(s.resample)
+		case "structure":
+			opt = paintbrush.WithScorer(paintbrush.StructuralScorer{Structure: s.structure})
+		}
+		if opt != nil {
+			opts = append(opts, opt)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// write writes a result to w in the output format.
+func (s *settings) write(w io.Writer, result *paintbrush.Result) error {
+	var err error
+	switch s.format {
+	case paintbrush.FormatHTML:
+		_, err = result.WriteHTML(w, s.html)
+	case paintbrush.FormatSVG:
+		_, err = result.WriteSVG(w, s.svg)
+	default:
+		_, err = result.WriteFormat(w, s.format)
+	}
+	return err
+}
+
+// loadPreset returns the built-in preset with the given name, or else loads
+// the preset file at that path.
+func loadPreset(name string) (paintbrush.Preset, error) {
+	preset, err := paintbrush.BuiltinPreset(name)
+	if err == nil {
+		return preset, nil
+	}
+	if _, statErr := os.Stat(name); errors.Is(statErr, os.ErrNotExist) {
+		return paintbrush.Preset{}, err
+	}
+	return paintbrush.LoadPreset(name)
+}
+
+// parseSize parses a size given as WIDTHxHEIGHT.
+func parseSize(s string) (width, height int, err error) {
+	w, h, ok := strings.Cut(s, "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("invalid size %q, expected WIDTHxHEIGHT", s)
+	}
+	return width, height, nil
+}
+
+// parseRuneRange parses a range of code points given as START-END.
+func parseRuneRange(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if ok {
+		start, err = parseCodePoint(from)
+		if err == nil {
+			end, err = parseCodePoint(to)
+		}
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("invalid rune range %q, expected START-END", s)
+	}
+	return start, end, nil
+}
+
+// parseCodePoint parses a code point given in decimal, as 0x hexadecimal or
+// in U+ notation.
+func parseCodePoint(s string) (int, error) {
+	if hex, ok := strings.CutPrefix(strings.ToUpper(s), "U+"); ok {
+		s = "0x" + hex
+	}
+	v, err := strconv.ParseInt(s, 0, 32)
+	return int(v), err
+}
+
+// parseWeights parses CHAR=WEIGHT pairs separated by commas. The character is
+// read before looking for the separator, so commas and equals signs can be
+// weighted too.
+func parseWeights(s string) (map[rune]float64, error) {
+	weights := make(map[rune]float64)
+	for s != "" {
+		char, size := utf8.DecodeRuneInString(s)
+		rest, ok := strings.CutPrefix(s[size:], "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weights %q, expected CHAR=WEIGHT pairs", s)
+		}
+		value, next, _ := strings.Cut(rest, ",")
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("invalid weight %q for %q", value, char)
+		}
+		weights[char] = weight
+		s = next
+	}
+	return weights, nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestParseWeights(t *testing.T) {
+	weights, err := parseWeights("█=0.85,▀=0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weights) != 2 || weights['█'] != 0.85 || weights['▀'] != 0.9 {
+		t.Errorf("parseWeights = %v", weights)
+	}
+
+	for _, s := range []string{"#=NaN", "#=Inf", "#=-Inf", "#=+Inf", "#=1e999", "#", "#=x"} {
+		if _, err := parseWeights(s); err == nil {
+			t.Errorf("parseWeights(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	paintbrush "github.com/jordanella/go-ansi-paintbrush"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// runWatch executes the watch command and returns the exit code.
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("paintbrush watch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	var s settings
+	s.register(fs)
+	interval := fs.Duration("interval", 500*time.Millisecond, "how often the files are checked for changes")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage(fs, stdout, "paintbrush watch [flags] image",
+				"Converts an image and converts it again whenever the image, the font\n"+
+					"file or the preset file changes, redrawing the terminal. Stop with\n"+
+					"Ctrl+C.")
+			return exitOK
+		}
+		return usageError(stderr, err)
+	}
+	if fs.NArg() != 1 || fs.Arg(0) == "-" {
+		return usageError(stderr, errors.New("watch expects the path of one image"))
+	}
+	if *interval <= 0 {
+		return usageError(stderr, fmt.Errorf("invalid interval %v", *interval))
+	}
+	if _, err := s.canvas(fs); err != nil {
+		return usageError(stderr, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	path := fs.Arg(0)
+	watch(ctx, s.watchedFiles(path), ticker.C, func(ctx context.Context) {
+		draw(ctx, &s, fs, path, stdout, stderr)
+	})
+	return exitOK
+}
+
+// watchedFiles returns the files a rendering of the image at path depends
+// on: the image itself, the font file and the preset file.
+func (s *settings) watchedFiles(path string) []string {
+	paths := []string{path}
+	if s.font != "" {
+		paths = append(paths, s.font)
+	}
+	if s.preset != "" {
+		if _, err := paintbrush.BuiltinPreset(s.preset); err != nil {
+			paths = append(paths, s.preset)
+		}
+	}
+	return paths
+}
+
+// draw converts the image at path with the current contents of the font and
+// preset files, then clears the terminal and writes the result, or the error
+// that stopped it. Nothing is drawn if ctx is cancelled first.
+func draw(ctx context.Context, s *settings, fs *flag.FlagSet, path string, stdout, stderr io.Writer) {
+	canvas, err := s.canvas(fs)
+	var result *paintbrush.Result
+	if err == nil {
+		var img image.Image
+		img, err = readImage(path, nil)
+		if err == nil {
+			canvas.SetImage(img)
+			result, err = canvas.PaintContext(ctx)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	if err == nil {
+		s.write(&buf, result)
+		buf.WriteByte('\n')
+	}
+	stdout.Write(buf.Bytes())
+	if err != nil {
+		fmt.Fprintf(stderr, "paintbrush: %s\n", message(err))
+	}
+}
+
+// fileState identifies a version of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// statFile returns the state of the file at path. Files that cannot be read
+// have the zero state, so that creating them again counts as a change.
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// watch calls render once, and again whenever one of the files at paths has
+// changed when a tick is received, until ctx is cancelled. A render still in
+// progress is cancelled, and has returned, before the next one starts. Ticks
+// come from the caller so that tests can check for changes on demand.
+func watch(ctx context.Context, paths []string, ticks <-chan time.Time, render func(context.Context)) {
+	states := make([]fileState, len(paths))
+	for i, path := range paths {
+		states[i] = statFile(path)
+	}
+
+	var cancel context.CancelFunc
+	var done chan struct{}
+	start := func() {
+		var renderCtx context.Context
+		renderCtx, cancel = context.WithCancel(ctx)
+		done = make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			render(renderCtx)
+		}(done)
+	}
+	start()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		case <-ticks:
+			changed := false
+			for i, path := range paths {
+				if state := statFile(path); state != states[i] {
+					states[i] = state
+					changed = true
+				}
+			}
+			if changed {
+				cancel()
+				<-done
+				start()
+			}
+		}
+	}
+}
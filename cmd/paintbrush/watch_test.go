@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "image.png")
+	preset := filepath.Join(dir, "preset.json")
+	for _, path := range []string{image, preset} {
+		if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every render reports its context and blocks until it is cancelled
+	started := make(chan context.Context)
+	render := func(ctx context.Context) {
+		started <- ctx
+		<-ctx.Done()
+	}
+	ticks := make(chan time.Time)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		watch(ctx, []string{image, preset}, ticks, render)
+	}()
+
+	current := <-started
+	expectRestart := func(change string) {
+		t.Helper()
+		previous := current
+		ticks <- time.Time{}
+		select {
+		case current = <-started:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s did not start a new render", change)
+		}
+		if previous.Err() == nil {
+			t.Fatalf("%s did not cancel the running render", change)
+		}
+	}
+
+	// The second tick is only received once the first one has been handled
+	ticks <- time.Time{}
+	ticks <- time.Time{}
+	if current.Err() != nil {
+		t.Fatal("render was cancelled although no file changed")
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(image, later, later); err != nil {
+		t.Fatal(err)
+	}
+	expectRestart("touching the image")
+
+	if err := os.WriteFile(preset, []byte("version 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectRestart("rewriting the preset")
+
+	if err := os.Remove(preset); err != nil {
+		t.Fatal(err)
+	}
+	expectRestart("removing the preset")
+
+	if err := os.WriteFile(preset, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectRestart("creating the preset again")
+
+	cancel()
+	<-stopped
+	if current.Err() == nil {
+		t.Error("the last render was not cancelled when watching stopped")
+	}
+}
@@ -0,0 +1,192 @@
+package paintbrush
+
+import (
+	"fmt"
+	"math"
+)
+
+// ColorMode selects the palette that cell colors are quantized to and the
+// escape sequences used to emit them.
+type ColorMode int
+
+const (
+	ColorModeTrueColor  ColorMode = iota // 24-bit "38;2;r;g;b" sequences
+	ColorMode256                         // xterm 256-color "38;5;n" sequences
+	ColorMode16                          // Basic ANSI 16-color sequences
+	ColorModeMonochrome                  // No color sequences at all
+)
+
+// String returns the name of the color mode.
+func (m ColorMode) String() string {
+	switch m {
+	case ColorModeTrueColor:
+		return "truecolor"
+	case ColorMode256:
+		return "256"
+	case ColorMode16:
+		return "16"
+	case ColorModeMonochrome:
+		return "monochrome"
+	}
+	return fmt.Sprintf("ColorMode(%d)", int(m))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (m ColorMode) MarshalText() ([]byte, error) {
+	return marshalEnum(m, ColorModeMonochrome)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (m *ColorMode) UnmarshalText(text []byte) error {
+	return unmarshalEnum(m, ColorModeMonochrome, text)
+}
+
+// ansi16Palette holds the xterm default values of the 16 basic ANSI colors.
+var ansi16Palette = [16]Pixel{
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// cubeLevels holds the channel values of the xterm 6x6x6 color cube.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// xterm256Color returns the color of the given xterm palette index.
+func xterm256Color(index int) Pixel {
+	switch {
+	case index < 16:
+		return ansi16Palette[index]
+	case index < 232:
+		index -= 16
+		return Pixel{cubeLevels[index/36], cubeLevels[(index/6)%6], cubeLevels[index%6], 255}
+	default:
+		gray := uint8(8 + 10*(index-232))
+		return Pixel{gray, gray, gray, 255}
+	}
+}
+
+// cubeLevelsAround returns the indices of the cube levels just below and
+// just above v.
+func cubeLevelsAround(v float64) (int, int) {
+	value := v * 255
+	for i := 1; i < len(cubeLevels); i++ {
+		if value < float64(cubeLevels[i]) {
+			return i - 1, i
+		}
+	}
+	return len(cubeLevels) - 1, len(cubeLevels) - 1
+}
+
+// palette pairs a color mode with the metric used to pick its entries.
+type palette struct {
+	mode   ColorMode
+	metric ColorMetric
+}
+
+// paletteIndex returns the palette entry closest to v. Only the color cube
+// and gray ramp are considered in 256-color mode, since the first 16 entries
+// depend on the terminal theme.
+func (p palette) paletteIndex(v Vec4) int {
+	switch p.mode {
+	case ColorMode256:
+		// The closest entry is one of the corners of the surrounding cube
+		// cell or one of the grays either side of the color's mean
+		r0, r1 := cubeLevelsAround(v.R)
+		g0, g1 := cubeLevelsAround(v.G)
+		b0, b1 := cubeLevelsAround(v.B)
+		gray := int(math.Floor(((v.R+v.G+v.B)/3*255 - 8) / 10))
+
+		candidates := []int{
+			232 + max(0, min(23, gray)), 232 + max(0, min(23, gray+1)),
+		}
+		for _, r := range [2]int{r0, r1} {
+			for _, g := range [2]int{g0, g1} {
+				for _, b := range [2]int{b0, b1} {
+					candidates = append(candidates, 16+36*r+6*g+b)
+				}
+			}
+		}
+
+		best, bestDist := 0, math.MaxFloat64
+		for _, index := range candidates {
+			if d := p.metric.distance(v, xterm256Color(index).toVec4(v.A)); d < bestDist {
+				best, bestDist = index, d
+			}
+		}
+		return best
+	case ColorMode16:
+		best, bestDist := 0, math.MaxFloat64
+		for i, entry := range ansi16Palette {
+			if d := p.metric.distance(v, entry.toVec4(v.A)); d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		return best
+	}
+	return -1
+}
+
+// quantize snaps the color to the closest color the mode can display. Alpha is
+// preserved, and fully transparent colors are left untouched.
+func (p palette) quantize(v Vec4) Vec4 {
+	if v.A == 0 {
+		return v
+	}
+
+	switch p.mode {
+	case ColorMode256:
+		return xterm256Color(p.paletteIndex(v)).toVec4(v.A)
+	case ColorMode16:
+		return ansi16Palette[p.paletteIndex(v)].toVec4(v.A)
+	case ColorModeMonochrome:
+		// The terminal's default foreground
+		return Vec4{1, 1, 1, v.A}
+	}
+	return v
+}
+
+// quantizeBg snaps a background color to the palette. Monochrome output
+// leaves the terminal's default background, which is scored as transparent.
+func (p palette) quantizeBg(v Vec4) Vec4 {
+	if p.mode == ColorModeMonochrome {
+		return Vec4{}
+	}
+	return p.quantize(v)
+}
+
+// ansiFg returns the escape sequence selecting v as the foreground color.
+func (p palette) ansiFg(v Vec4) string {
+	switch p.mode {
+	case ColorMode256:
+		return fmt.Sprintf("\033[38;5;%dm", p.paletteIndex(v))
+	case ColorMode16:
+		index := p.paletteIndex(v)
+		if index < 8 {
+			return fmt.Sprintf("\033[%dm", 30+index)
+		}
+		return fmt.Sprintf("\033[%dm", 90+index-8)
+	case ColorModeMonochrome:
+		return ""
+	}
+	return v.ToPixel().AnsiFg()
+}
+
+// ansiBg returns the escape sequence selecting v as the background color.
+func (p palette) ansiBg(v Vec4) string {
+	switch p.mode {
+	case ColorMode256:
+		return fmt.Sprintf("\033[48;5;%dm", p.paletteIndex(v))
+	case ColorMode16:
+		index := p.paletteIndex(v)
+		if index < 8 {
+			return fmt.Sprintf("\033[%dm", 40+index)
+		}
+		return fmt.Sprintf("\033[%dm", 100+index-8)
+	case ColorModeMonochrome:
+		return "\033[0m"
+	}
+	return v.ToPixel().AnsiBg()
+}
@@ -0,0 +1,35 @@
+package paintbrush
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMonochromeUsesGlyphShapes(t *testing.T) {
+	canvas := New()
+	canvas.SetWidth(30)
+	canvas.SetColorMode(ColorModeMonochrome)
+	result, err := canvas.Render(context.Background(), loadNorman(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := result.Text()
+	if strings.Contains(text, "\033[38") || strings.Contains(text, "\033[48") {
+		t.Error("monochrome output contains color escape codes")
+	}
+	runes := make(map[rune]bool)
+	for _, row := range result.Grid() {
+		for _, cell := range row {
+			runes[cell.Rune] = true
+			if cell.Bg.A != 0 {
+				t.Fatalf("cell background %v, want the terminal default", cell.Bg)
+			}
+		}
+	}
+	delete(runes, ' ')
+	if len(runes) < 5 {
+		t.Errorf("monochrome render uses %d runes besides the space, want glyph shapes", len(runes))
+	}
+}
@@ -0,0 +1,78 @@
+package paintbrush
+
+import (
+	"os"
+	"strings"
+)
+
+// DetectColorMode returns the best color mode for the environment of the
+// current process.
+func DetectColorMode() ColorMode {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	return DetectColorModeFromEnv(env)
+}
+
+// DetectColorModeFromEnv returns the best color mode for the given set of
+// environment variables. NO_COLOR disables color entirely, FORCE_COLOR and
+// CLICOLOR_FORCE enable it even for unknown terminals, and otherwise the mode
+// is inferred from COLORTERM, TERM_PROGRAM and TERM.
+func DetectColorModeFromEnv(env map[string]string) ColorMode {
+	if env["NO_COLOR"] != "" {
+		return ColorModeMonochrome
+	}
+
+	forced := false
+	if level, ok := env["FORCE_COLOR"]; ok {
+		switch strings.ToLower(level) {
+		case "0", "false":
+			return ColorModeMonochrome
+		case "2":
+			return ColorMode256
+		case "3":
+			return ColorModeTrueColor
+		}
+		forced = true
+	}
+	if value := env["CLICOLOR_FORCE"]; value != "" && value != "0" {
+		forced = true
+	}
+	if env["CLICOLOR"] == "0" && !forced {
+		return ColorModeMonochrome
+	}
+
+	switch strings.ToLower(env["COLORTERM"]) {
+	case "truecolor", "24bit":
+		return ColorModeTrueColor
+	}
+
+	switch env["TERM_PROGRAM"] {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper", "ghostty":
+		return ColorModeTrueColor
+	case "Apple_Terminal":
+		return ColorMode256
+	}
+
+	if env["WT_SESSION"] != "" {
+		return ColorModeTrueColor
+	}
+
+	term := strings.ToLower(env["TERM"])
+	switch {
+	case term == "" || term == "dumb":
+		if forced {
+			return ColorMode16
+		}
+		return ColorModeMonochrome
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"), strings.Contains(term, "direct"),
+		strings.Contains(term, "kitty"), strings.Contains(term, "alacritty"), strings.Contains(term, "foot"):
+		return ColorModeTrueColor
+	case strings.Contains(term, "256"):
+		return ColorMode256
+	}
+	return ColorMode16
+}
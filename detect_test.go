@@ -0,0 +1,42 @@
+package paintbrush
+
+import "testing"
+
+func TestDetectColorModeFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want ColorMode
+	}{
+		{"empty environment", map[string]string{}, ColorModeMonochrome},
+		{"NO_COLOR", map[string]string{"NO_COLOR": "1", "TERM": "xterm-256color"}, ColorModeMonochrome},
+		{"NO_COLOR over FORCE_COLOR", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "3", "COLORTERM": "truecolor"}, ColorModeMonochrome},
+		{"empty NO_COLOR", map[string]string{"NO_COLOR": "", "TERM": "xterm-256color"}, ColorMode256},
+		{"FORCE_COLOR=0", map[string]string{"FORCE_COLOR": "0", "COLORTERM": "truecolor"}, ColorModeMonochrome},
+		{"FORCE_COLOR=false", map[string]string{"FORCE_COLOR": "false", "TERM": "xterm"}, ColorModeMonochrome},
+		{"FORCE_COLOR=2", map[string]string{"FORCE_COLOR": "2", "TERM": "dumb"}, ColorMode256},
+		{"FORCE_COLOR=3", map[string]string{"FORCE_COLOR": "3"}, ColorModeTrueColor},
+		{"FORCE_COLOR=1 without TERM", map[string]string{"FORCE_COLOR": "1"}, ColorMode16},
+		{"FORCE_COLOR over CLICOLOR=0", map[string]string{"FORCE_COLOR": "1", "CLICOLOR": "0", "TERM": "xterm-256color"}, ColorMode256},
+		{"CLICOLOR=0", map[string]string{"CLICOLOR": "0", "TERM": "xterm-256color"}, ColorModeMonochrome},
+		{"CLICOLOR=0 with CLICOLOR_FORCE", map[string]string{"CLICOLOR": "0", "CLICOLOR_FORCE": "1", "TERM": "xterm-256color"}, ColorMode256},
+		{"CLICOLOR=0 with CLICOLOR_FORCE=0", map[string]string{"CLICOLOR": "0", "CLICOLOR_FORCE": "0", "TERM": "xterm-256color"}, ColorModeMonochrome},
+		{"COLORTERM=truecolor", map[string]string{"COLORTERM": "truecolor", "TERM": "xterm"}, ColorModeTrueColor},
+		{"COLORTERM=24bit", map[string]string{"COLORTERM": "24bit"}, ColorModeTrueColor},
+		{"TERM_PROGRAM", map[string]string{"TERM_PROGRAM": "Apple_Terminal", "TERM": "xterm"}, ColorMode256},
+		{"TERM=xterm-256color", map[string]string{"TERM": "xterm-256color"}, ColorMode256},
+		{"TERM=xterm-direct", map[string]string{"TERM": "xterm-direct"}, ColorModeTrueColor},
+		{"TERM=xterm", map[string]string{"TERM": "xterm"}, ColorMode16},
+		{"TERM=dumb", map[string]string{"TERM": "dumb"}, ColorModeMonochrome},
+		{"TERM=dumb with FORCE_COLOR", map[string]string{"TERM": "dumb", "FORCE_COLOR": "1"}, ColorMode16},
+		{"TERM=dumb with CLICOLOR_FORCE", map[string]string{"TERM": "dumb", "CLICOLOR_FORCE": "1"}, ColorMode16},
+		{"empty TERM with CLICOLOR_FORCE", map[string]string{"TERM": "", "CLICOLOR_FORCE": "1"}, ColorMode16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectColorModeFromEnv(tt.env); got != tt.want {
+				t.Errorf("DetectColorModeFromEnv(%v) = %v, want %v", tt.env, got, tt.want)
+			}
+		})
+	}
+}
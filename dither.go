@@ -0,0 +1,200 @@
+package paintbrush
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dither selects how color error is spread between neighbouring cells.
+type Dither int
+
+const (
+	DitherNone           Dither = iota // Every cell is solved independently
+	DitherFloydSteinberg               // Floyd–Steinberg error diffusion
+	DitherAtkinson                     // Atkinson error diffusion, diffusing 3/4 of the error
+	DitherSierra                       // Three row Sierra error diffusion
+	DitherBayer                        // Ordered dithering with an 8x8 Bayer matrix
+)
+
+// String returns the name of the dithering method.
+func (d Dither) String() string {
+	switch d {
+	case DitherNone:
+		return "none"
+	case DitherFloydSteinberg:
+		return "floyd-steinberg"
+	case DitherAtkinson:
+		return "atkinson"
+	case DitherSierra:
+		return "sierra"
+	case DitherBayer:
+		return "bayer"
+	}
+	return fmt.Sprintf("Dither(%d)", int(d))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (d Dither) MarshalText() ([]byte, error) {
+	return marshalEnum(d, DitherBayer)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (d *Dither) UnmarshalText(text []byte) error {
+	return unmarshalEnum(d, DitherBayer, text)
+}
+
+// ditherTap moves a share of a cell's error to the cell at the given offset.
+type ditherTap struct {
+	dx, dy int
+	weight float64
+}
+
+var ditherKernels = map[Dither][]ditherTap{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	},
+	DitherSierra: {
+		{1, 0, 5.0 / 32}, {2, 0, 3.0 / 32},
+		{-2, 1, 2.0 / 32}, {-1, 1, 4.0 / 32}, {0, 1, 5.0 / 32}, {1, 1, 4.0 / 32}, {2, 1, 3.0 / 32},
+		{-1, 2, 2.0 / 32}, {0, 2, 3.0 / 32}, {1, 2, 2.0 / 32},
+	},
+}
+
+// bayerMatrix is the 8x8 threshold map used for ordered dithering.
+var bayerMatrix = [8][8]int{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// diffuses reports whether the method spreads error between cells, which
+// requires cells to be solved after the neighbours they receive error from.
+func (d Dither) diffuses() bool {
+	_, ok := ditherKernels[d]
+	return ok
+}
+
+// waveSlope returns the smallest k for which every cell at x+k*y only
+// receives error from cells with a smaller x+k*y. Cells sharing a value form
+// a wavefront that can be solved in parallel.
+func (d Dither) waveSlope() int {
+	slope := 1
+	for _, tap := range ditherKernels[d] {
+		for tap.dy > 0 && tap.dx+slope*tap.dy <= 0 {
+			slope++
+		}
+	}
+	return slope
+}
+
+// orderedBias returns the ordered dithering offset of a cell. The offset is
+// scaled to the spacing of the palette, so that it nudges cells across
+// quantization steps without visibly tinting truecolor output.
+func (c *Canvas) orderedBias(charX, charY int) Vec4 {
+	spread := 1.0 / 16
+	switch c.palette.mode {
+	case ColorMode256:
+		spread = 1.0 / 6
+	case ColorMode16:
+		spread = 1.0 / 3
+	case ColorModeMonochrome:
+		spread = 1.0 / 2
+	}
+	t := (float64(bayerMatrix[charY%8][charX%8])+0.5)/64 - 0.5
+	return Vec4{t * spread, t * spread, t * spread, 0}
+}
+
+// diffusedError returns the error a cell receives from already solved
+// neighbours.
+func (c *Canvas) diffusedError(task Task, taskResults []TaskResult, width, height int) Vec4 {
+	var bias Vec4
+	for _, tap := range ditherKernels[c.Dither] {
+		x, y := task.CharX-tap.dx, task.CharY-tap.dy
+		if x < 0 || x >= width || y < 0 || y >= height {
+			continue
+		}
+		bias = bias.Add(taskResults[y*width+x].Residual.Mul(tap.weight))
+	}
+	return bias
+}
+
+// applyBias offsets a premultiplied sample by the dithering bias of its cell,
+// keeping the result within the range its alpha allows.
+func applyBias(col, bias Vec4) Vec4 {
+	if bias == (Vec4{}) {
+		return col
+	}
+	clamp := func(v float64) float64 {
+		return max(0, min(col.A, v))
+	}
+	return Vec4{
+		R: clamp(col.R + bias.R*col.A),
+		G: clamp(col.G + bias.G*col.A),
+		B: clamp(col.B + bias.B*col.A),
+		A: col.A,
+	}
+}
+
+// residual returns the mean difference between the biased samples of a cell
+// and the cell as rendered, which is the error diffused to its neighbours.
+func (c *Canvas) residual(result TaskResult, bias Vec4, imgCharWidth, imgCharHeight float64) Vec4 {
+	imgXBegin := float64(result.CharX) * imgCharWidth
+	imgYBegin := float64(result.CharY) * imgCharHeight
+
+	var sum Vec4
+	for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
+		for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
+			imgX := imgXBegin + imgCharWidth*(float64(fontCharX)+0.5)/float64(c.Font.GlyphWidth)
+			imgY := imgYBegin + imgCharHeight*(float64(fontCharY)+0.5)/float64(c.Font.GlyphHeight)
+			col := applyBias(c.readImageColor(imgX, imgY), bias)
+			fg := float64(result.Glyph.Pixels[fontCharX+fontCharY*c.Font.GlyphWidth]) / 255.0
+			sum = sum.Add(col.Sub(result.Fg.Mul(fg).Add(result.Bg.Mul(1 - fg))))
+		}
+	}
+	sum.A = 0
+	return sum.Div(float64(c.Font.GlyphWidth * c.Font.GlyphHeight))
+}
+
+// paintWavefront feeds the tasks to the workers one wavefront at a time, so
+// that every cell has received the error of its neighbours before it is
+// solved while the cells within a wavefront still render in parallel.
+func (c *Canvas) paintWavefront(ctx context.Context, taskChan chan<- Task, resultChan <-chan TaskResult, taskResults []TaskResult, width, height int, collect func(TaskResult) error) error {
+	slope := c.Dither.waveSlope()
+	waves := make([][]Task, width+slope*(height-1))
+	for charY := 0; charY < height; charY++ {
+		for charX := 0; charX < width; charX++ {
+			t := charX + slope*charY
+			waves[t] = append(waves[t], Task{CharX: charX, CharY: charY})
+		}
+	}
+
+	for _, wave := range waves {
+		for _, task := range wave {
+			task.Bias = c.diffusedError(task, taskResults, width, height)
+			taskChan <- task
+		}
+		for range wave {
+			select {
+			case result := <-resultChan:
+				if err := collect(result); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package paintbrush
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+)
+
+var (
+	// ErrNoImage is returned when painting without an image set.
+	ErrNoImage = errors.New("paintbrush: no image set")
+
+	// ErrNoGlyphs is returned when no glyph is eligible for rendering, for
+	// example because every character of the font is forbidden.
+	ErrNoGlyphs = errors.New("paintbrush: no glyphs available")
+
+	// ErrInvalidDimensions is returned when the image, output or glyph
+	// dimensions leave nothing to render.
+	ErrInvalidDimensions = errors.New("paintbrush: invalid dimensions")
+
+	// ErrFontParse is returned when font data cannot be parsed.
+	ErrFontParse = errors.New("paintbrush: cannot parse font")
+
+	// ErrInvalidOption is returned by NewWithOptions for settings outside
+	// their valid range.
+	ErrInvalidOption = errors.New("paintbrush: invalid option")
+
+	// ErrInvalidPreset is returned when preset data is not valid JSON or
+	// contains unknown settings.
+	ErrInvalidPreset = errors.New("paintbrush: invalid preset")
+
+	// ErrUnknownPreset is returned for names that are not built-in presets.
+	ErrUnknownPreset = errors.New("paintbrush: unknown preset")
+)
+
+// discardLogger drops every record, for canvases without a logger.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// logger returns the logger warnings are reported to.
+func (c *Canvas) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return discardLogger
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	_ "image/png"
-	"time"
 
 	paintbrush "github.com/jordanella/go-ansi-paintbrush"
 )
@@ -61,13 +60,19 @@ func main() {
 	canvas.Weights = weights
 
 	// You can render asynchronously and monitor progress if desired
-	canvas.StartPainting()
-	for canvas.GetProgress() < 1.0 {
-		fmt.Printf("Rendering progress: %.2f%%\r", canvas.GetProgress()*100)
-		time.Sleep(100 * time.Millisecond)
+	painting := canvas.StartPainting()
+	for progress := range painting.Progress() {
+		fmt.Printf("Rendering progress: %.2f%%\r", float64(progress.Done)/float64(progress.Total)*100)
 	}
 
-	fmt.Printf("\r%s", canvas.Result)
+	// The progress channel is closed once painting has ended
+	result, err := painting.Wait()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("\r%s", result.Text())
 
 	// You can also get the C-style string or Bash command if needed:
 	//fmt.Println(canvas.GetResultC())
@@ -27,6 +27,54 @@ type Glyph struct {
 	UTF8    string
 	Pixels  []uint8
 	Weight  float64
+
+	fit *glyphFit // Coverage data precomputed for fitting colors
+}
+
+// glyphFit holds a glyph's coverage and the sums over it that fitting a
+// foreground and background color to a cell depends on, so that only the
+// products with the cell's pixels remain to be computed per cell.
+type glyphFit struct {
+	coverage []float64 // Pixels scaled to 0..1
+	ink      []int     // Indices of the pixels with any coverage
+	sumF     float64   // Σ f
+	sumB     float64   // Σ (1-f)
+	sumFF    float64   // Σ f²
+	sumFB    float64   // Σ f(1-f)
+	sumBB    float64   // Σ (1-f)²
+
+	width int         // Width of the glyph the data was computed for
+	shape orientation // Orientation of the coverage, for StructuralScorer
+}
+
+func newGlyphFit(pixels []uint8, width int) *glyphFit {
+	fit := &glyphFit{coverage: make([]float64, len(pixels)), width: width}
+	for i, pixel := range pixels {
+		f := float64(pixel) / 255.0
+		fit.coverage[i] = f
+		if pixel != 0 {
+			fit.ink = append(fit.ink, i)
+		}
+		fit.sumF += f
+		fit.sumB += 1 - f
+		fit.sumFF += f * f
+		fit.sumFB += f * (1 - f)
+		fit.sumBB += (1 - f) * (1 - f)
+	}
+	if width > 0 {
+		fit.shape = gradientOrientation(width, len(pixels)/width, func(i int) float64 { return fit.coverage[i] })
+	}
+	return fit
+}
+
+// fitData returns the glyph's precomputed coverage data for glyphs of the
+// given width, computing it on the fly for glyphs the canvas has not
+// prepared.
+func (g *Glyph) fitData(width int) *glyphFit {
+	if g.fit != nil && len(g.fit.coverage) == len(g.Pixels) && g.fit.width == width {
+		return g.fit
+	}
+	return newGlyphFit(g.Pixels, width)
 }
 
 // LoadFont loads a font from the specified file path.
@@ -42,17 +90,112 @@ func (c *Canvas) LoadFont(path string) error {
 	return nil
 }
 
-// SetFont sets the font using the provided byte slice of font data.
-func (c *Canvas) SetFont(data []byte) error {
-	f, err := truetype.Parse(data)
-	if err != nil {
-		return err
+// fontParams are the canvas settings a font's glyphs were rasterized with.
+type fontParams struct {
+	glyphWidth, glyphHeight int
+	runeStart, runeLimit    int
+	aspectRatio             float64
+	weighted                map[rune]struct{} // Weighted runes rasterized besides the rune range
+}
+
+// currentFontParams returns the settings the font would be rasterized with
+// now.
+func (c *Canvas) currentFontParams() fontParams {
+	return fontParams{
+		glyphWidth:  c.GlyphWidth,
+		glyphHeight: c.GlyphHeight,
+		runeStart:   c.RuneStart,
+		runeLimit:   c.RuneLimit,
+		aspectRatio: c.AspectRatio,
 	}
+}
 
-	// Set fixed glyph dimensions
+// fontStale reports whether the font has to be rasterized again before
+// painting, because there is none yet or the glyph dimensions, rune limits,
+// aspect ratio or weighted runes have changed since it was set.
+func (c *Canvas) fontStale() bool {
+	if len(c.Font.Glyphs) == 0 {
+		return true
+	}
+	if c.fontData == nil {
+		// Fonts assigned directly cannot be rasterized again
+		return false
+	}
+	current := c.currentFontParams()
+	if current.glyphWidth != c.fontParams.glyphWidth || current.glyphHeight != c.fontParams.glyphHeight ||
+		current.runeStart != c.fontParams.runeStart || current.runeLimit != c.fontParams.runeLimit ||
+		current.aspectRatio != c.fontParams.aspectRatio {
+		return true
+	}
+	// Weighted runes outside the rune range are only rasterized while they
+	// are weighted, so adding or removing one changes the glyphs
+	outside := func(r rune) bool {
+		return r < rune(c.RuneStart) || r >= rune(c.RuneLimit)
+	}
+	for r := range c.Weights {
+		if _, ok := c.fontParams.weighted[r]; !ok && outside(r) {
+			return true
+		}
+	}
+	for r := range c.fontParams.weighted {
+		if _, ok := c.Weights[r]; !ok && outside(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFontDimensions sets the glyph dimensions and aspect of the font from the
+// canvas settings.
+func (c *Canvas) setFontDimensions() {
 	c.Font.GlyphWidth = c.GlyphWidth // You can adjust these values
 	c.Font.GlyphHeight = c.GlyphHeight
 	c.Font.Aspect = (float64(c.Font.GlyphHeight) / (float64(c.Font.GlyphWidth))) * c.AspectRatio
+}
+
+// prepareFont makes the font ready for painting in the render mode. Block
+// modes draw their own glyphs, so for them the font is neither loaded nor
+// rasterized and only its dimensions are brought up to date.
+func (c *Canvas) prepareFont() error {
+	if c.RenderMode == RenderGlyphs {
+		return c.ensureFont()
+	}
+	if c.fontStale() {
+		c.setFontDimensions()
+	}
+	return nil
+}
+
+// ensureFont rasterizes the font again if it is stale, falling back to the
+// embedded font when none has been set.
+func (c *Canvas) ensureFont() error {
+	if !c.fontStale() {
+		return nil
+	}
+	data := c.fontData
+	if data == nil {
+		var err error
+		data, err = EmbeddedFonts.ReadFile(FiraMonoRegular)
+		if err != nil {
+			return err
+		}
+	}
+	return c.SetFont(data)
+}
+
+// SetFont sets the font using the provided byte slice of font data. The
+// glyphs are rasterized with the current glyph dimensions, rune limits and
+// aspect ratio, and rasterized again when painting after any of them change.
+func (c *Canvas) SetFont(data []byte) error {
+	f, err := truetype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFontParse, err)
+	}
+	c.fontData = data
+	c.fontParams = c.currentFontParams()
+	c.fontParams.weighted = make(map[rune]struct{}, len(c.Weights))
+
+	c.setFontDimensions()
 
 	// Set font size and DPI
 	opts := truetype.Options{
@@ -67,27 +210,27 @@ func (c *Canvas) SetFont(data []byte) error {
 	for r := rune(c.RuneStart); r < rune(c.RuneLimit); r++ {
 		glyph, err := c.generateGlyph(face, r)
 		if err != nil {
-			fmt.Printf("Error generating glyph for rune %d: %v\n", r, err)
+			c.logger().Warn("skipping glyph", "rune", string(r), "error", err)
 			continue
 		}
 		glyph.Weight = 1.0 // Default weight
 		c.Font.Glyphs[r] = glyph
 	}
 
-	// Apply custom weights
-	for char, weight := range c.Weights {
-		if glyph, exists := c.Font.Glyphs[char]; exists {
-			glyph.Weight = weight
-			c.Font.Glyphs[char] = glyph
-		} else {
-			glyph, err := c.generateGlyph(face, char)
-			if err != nil {
-				fmt.Printf("Error generating glyph for rune %d: %v\n", char, err)
-				continue
-			}
-			glyph.Weight = weight
-			c.Font.Glyphs[char] = glyph
+	// Weighted characters outside the rune range are rasterized too. Their
+	// weights stay in Weights, which GetWeight reads when painting
+	for char := range c.Weights {
+		c.fontParams.weighted[char] = struct{}{}
+		if _, exists := c.Font.Glyphs[char]; exists {
+			continue
+		}
+		glyph, err := c.generateGlyph(face, char)
+		if err != nil {
+			c.logger().Warn("skipping weighted glyph", "rune", string(char), "error", err)
+			continue
 		}
+		glyph.Weight = 1.0
+		c.Font.Glyphs[char] = glyph
 	}
 
 	return nil
@@ -128,5 +271,6 @@ func (c *Canvas) generateGlyph(face font.Face, r rune) (Glyph, error) {
 		Unicode: int(r),
 		UTF8:    string(r),
 		Pixels:  pixels,
+		fit:     newGlyphFit(pixels, c.Font.GlyphWidth),
 	}, nil
 }
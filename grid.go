@@ -0,0 +1,62 @@
+package paintbrush
+
+// Cell is one character of a rendered image.
+type Cell struct {
+	Rune   rune
+	Fg, Bg Vec4 // Premultiplied sRGB colors; backgrounds with alpha below 0.5 are left unset
+}
+
+// Grid holds the cells of a rendered image, row by row. Grids returned by a
+// Result are copies that can be modified freely and turned back into every
+// output format with Result.WithGrid.
+type Grid [][]Cell
+
+// Width returns the length of the longest row of the grid.
+func (g Grid) Width() int {
+	width := 0
+	for _, row := range g {
+		width = max(width, len(row))
+	}
+	return width
+}
+
+// Height returns the number of rows of the grid.
+func (g Grid) Height() int {
+	return len(g)
+}
+
+// Clone returns a copy of the grid that shares no rows with it.
+func (g Grid) Clone() Grid {
+	clone := make(Grid, len(g))
+	for y, row := range g {
+		clone[y] = append([]Cell(nil), row...)
+	}
+	return clone
+}
+
+// newGrid arranges the results of a painting into rows. Cells without a
+// result are left blank.
+func newGrid(results []TaskResult, width, height int) Grid {
+	grid := make(Grid, height)
+	for y := range grid {
+		grid[y] = make([]Cell, width)
+		for x := range grid[y] {
+			grid[y][x].Rune = ' '
+		}
+	}
+	for _, result := range results {
+		if result.Glyph != nil {
+			grid[result.CharY][result.CharX] = newCell(result)
+		}
+	}
+	return grid
+}
+
+// newCell returns the cell for the result of a task. Tasks without a result
+// are blank.
+func newCell(result TaskResult) Cell {
+	if result.Glyph == nil {
+		return Cell{Rune: ' '}
+	}
+	return Cell{Rune: rune(result.Glyph.Unicode), Fg: result.Fg, Bg: result.Bg}
+}
@@ -42,12 +42,42 @@ func (v Vec4) Abs() Vec4 {
 	}
 }
 
+// ToLinear converts a premultiplied sRGB color to premultiplied linear RGB.
+func (v Vec4) ToLinear() Vec4 {
+	if v.A <= 0 {
+		return v
+	}
+	return Vec4{
+		R: srgbChannelToLinear(v.R/v.A) * v.A,
+		G: srgbChannelToLinear(v.G/v.A) * v.A,
+		B: srgbChannelToLinear(v.B/v.A) * v.A,
+		A: v.A,
+	}
+}
+
+// ToSRGB converts a premultiplied linear RGB color to premultiplied sRGB.
+func (v Vec4) ToSRGB() Vec4 {
+	if v.A <= 0 {
+		return v
+	}
+	return Vec4{
+		R: linearChannelToSRGB(v.R/v.A) * v.A,
+		G: linearChannelToSRGB(v.G/v.A) * v.A,
+		B: linearChannelToSRGB(v.B/v.A) * v.A,
+		A: v.A,
+	}
+}
+
+// ToPixel rounds the color to 8 bits per channel, clamping out of range values.
 func (v Vec4) ToPixel() Pixel {
+	channel := func(f float64) uint8 {
+		return uint8(math.Round(max(0, min(1, f)) * 255))
+	}
 	return Pixel{
-		R: uint8(v.R * 255),
-		G: uint8(v.G * 255),
-		B: uint8(v.B * 255),
-		A: uint8(v.A * 255),
+		R: channel(v.R),
+		G: channel(v.G),
+		B: channel(v.B),
+		A: channel(v.A),
 	}
 }
 
@@ -55,6 +85,10 @@ type Pixel struct {
 	R, G, B, A uint8
 }
 
+func (p Pixel) toVec4(alpha float64) Vec4 {
+	return Vec4{float64(p.R) / 255, float64(p.G) / 255, float64(p.B) / 255, alpha}
+}
+
 func (p Pixel) AnsiColor() string {
 	return fmt.Sprintf("2;%d;%d;%d", p.R, p.G, p.B)
 }
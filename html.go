@@ -0,0 +1,206 @@
+package paintbrush
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// HTMLOptions configures the HTML output of a result.
+type HTMLOptions struct {
+	Classes    bool   // Color cells with classes defined in a <style> element instead of inline styles
+	Standalone bool   // Wrap the output in a complete HTML document
+	FontFamily string // CSS font-family of the text, monospace when empty
+	Title      string // Title of a standalone document, left out when empty
+}
+
+// cssEscaper keeps font families from closing the <style> element.
+var cssEscaper = strings.NewReplacer("<", "\\3c ")
+
+// HTML returns the result as a <pre> element of colored spans.
+func (r *Result) HTML(opts HTMLOptions) string {
+	var sb strings.Builder
+	r.WriteHTML(&sb, opts)
+	return sb.String()
+}
+
+// WriteHTML writes the result to w as a <pre> element, one row at a time.
+// Adjacent cells of the same colors share a <span>, styled inline or with
+// classes named after their colors, such as pb-fg-ff8000 and pb-bg-000000.
+// Colors are those of the color mode the result was painted with, and
+// monochrome results are written without colors.
+func (r *Result) WriteHTML(w io.Writer, opts HTMLOptions) (int64, error) {
+	font := opts.FontFamily
+	if font == "" {
+		font = "monospace"
+	}
+
+	cw := &countingWriter{w: w}
+	if opts.Standalone {
+		io.WriteString(cw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+		if opts.Title != "" {
+			fmt.Fprintf(cw, "<title>%s</title>\n", html.EscapeString(opts.Title))
+		}
+	}
+	if opts.Classes {
+		r.writeHTMLStyles(cw, font)
+		io.WriteString(cw, "\n")
+	}
+	if opts.Standalone {
+		io.WriteString(cw, "</head>\n<body>\n")
+	}
+
+	if opts.Classes {
+		io.WriteString(cw, `<pre class="paintbrush">`)
+	} else {
+		fmt.Fprintf(cw, `<pre style="font-family:%s">`, html.EscapeString(font))
+	}
+	var row []byte
+	for y := range r.grid {
+		row = r.appendHTMLRow(row[:0], r.grid[y], opts.Classes)
+		if y < len(r.grid)-1 {
+			row = append(row, '\n')
+		}
+		cw.Write(row)
+		if cw.err != nil {
+			break
+		}
+	}
+	io.WriteString(cw, "</pre>")
+
+	if opts.Standalone {
+		io.WriteString(cw, "\n</body>\n</html>")
+	}
+	return cw.n, cw.err
+}
+
+// writeHTMLStyles writes a <style> element defining the classes of the <pre>
+// element and of every color in the result.
+func (r *Result) writeHTMLStyles(w io.Writer, font string) {
+	fgs := make(map[string]struct{})
+	bgs := make(map[string]struct{})
+	for _, row := range r.grid {
+		for _, cell := range row {
+			fg, bg := r.cssColors(cell)
+			if fg != "" {
+				fgs[fg] = struct{}{}
+			}
+			if bg != "" {
+				bgs[bg] = struct{}{}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "<style>\n.paintbrush{font-family:%s}\n", cssEscaper.Replace(font))
+	for _, fg := range sortedKeys(fgs) {
+		fmt.Fprintf(w, ".pb-fg-%s{color:#%s}\n", fg, fg)
+	}
+	for _, bg := range sortedKeys(bgs) {
+		fmt.Fprintf(w, ".pb-bg-%s{background-color:#%s}\n", bg, bg)
+	}
+	io.WriteString(w, "</style>")
+}
+
+// appendHTMLRow appends a row of cells as spans, starting a new span whenever
+// the colors change.
+func (r *Result) appendHTMLRow(buf []byte, row []Cell, classes bool) []byte {
+	var lastFg, lastBg string
+	open := false
+	for x, cell := range row {
+		fg, bg := r.cssColors(cell)
+		if x == 0 || fg != lastFg || bg != lastBg {
+			if open {
+				buf = append(buf, "</span>"...)
+			}
+			open = fg != "" || bg != ""
+			if open {
+				buf = appendHTMLSpan(buf, fg, bg, classes)
+			}
+			lastFg, lastBg = fg, bg
+		}
+
+		buf = appendEscapedRune(buf, cell.Rune)
+	}
+	if open {
+		buf = append(buf, "</span>"...)
+	}
+	return buf
+}
+
+// appendHTMLSpan appends the opening tag of a span with the given colors,
+// either of which may be empty.
+func appendHTMLSpan(buf []byte, fg, bg string, classes bool) []byte {
+	if classes {
+		buf = append(buf, `<span class="`...)
+		if fg != "" {
+			buf = append(buf, "pb-fg-"+fg...)
+		}
+		if fg != "" && bg != "" {
+			buf = append(buf, ' ')
+		}
+		if bg != "" {
+			buf = append(buf, "pb-bg-"+bg...)
+		}
+		return append(buf, `">`...)
+	}
+
+	buf = append(buf, `<span style="`...)
+	if fg != "" {
+		buf = append(buf, "color:#"+fg...)
+	}
+	if fg != "" && bg != "" {
+		buf = append(buf, ';')
+	}
+	if bg != "" {
+		buf = append(buf, "background-color:#"+bg...)
+	}
+	return append(buf, `">`...)
+}
+
+// appendEscapedRune appends a rune as HTML or XML text. Control characters,
+// which XML cannot contain, are replaced by spaces.
+func appendEscapedRune(buf []byte, char rune) []byte {
+	switch {
+	case char == '<':
+		return append(buf, "&lt;"...)
+	case char == '>':
+		return append(buf, "&gt;"...)
+	case char == '&':
+		return append(buf, "&amp;"...)
+	case char < ' ':
+		return append(buf, ' ')
+	}
+	return utf8.AppendRune(buf, char)
+}
+
+// cssColors returns the hexadecimal foreground and background colors of a
+// cell as the color mode displays them. Like the escape codes, the background
+// is left empty for transparent cells, and both for monochrome results.
+func (r *Result) cssColors(cell Cell) (fg, bg string) {
+	if r.palette.mode == ColorModeMonochrome {
+		return "", ""
+	}
+	if cell.Bg.A >= 0.5 {
+		bg = hexColor(r.palette.quantize(cell.Bg))
+	}
+	return hexColor(r.palette.quantize(cell.Fg)), bg
+}
+
+// hexColor formats a color as six hexadecimal digits.
+func hexColor(v Vec4) string {
+	p := v.ToPixel()
+	return fmt.Sprintf("%02x%02x%02x", p.R, p.G, p.B)
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
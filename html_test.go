@@ -0,0 +1,99 @@
+package paintbrush
+
+import (
+	"context"
+	"image"
+	"strings"
+	"testing"
+)
+
+var (
+	red   = Vec4{1, 0, 0, 1}
+	green = Vec4{0, 1, 0, 1}
+	blue  = Vec4{0, 0, 1, 1}
+)
+
+// testGrid has runs of equal colors, characters that need escaping and cells
+// with a transparent background.
+var testGrid = Grid{
+	{{'a', red, blue}, {'b', red, blue}, {'<', green, blue}, {'&', green, Vec4{}}},
+	{{'>', red, Vec4{}}, {' ', red, Vec4{}}},
+}
+
+// gridResult returns a result of the grid in the given color mode.
+func gridResult(t *testing.T, mode ColorMode, grid Grid) *Result {
+	t.Helper()
+	canvas := New()
+	canvas.SetWidth(4)
+	canvas.SetColorMode(mode)
+	result, err := canvas.Render(context.Background(), image.NewRGBA(image.Rect(0, 0, 28, 28)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.WithGrid(grid)
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		mode ColorMode
+		opts HTMLOptions
+		want string
+	}{
+		{
+			"inline styles", ColorModeTrueColor, HTMLOptions{},
+			`<pre style="font-family:monospace">` +
+				`<span style="color:#ff0000;background-color:#0000ff">ab</span>` +
+				`<span style="color:#00ff00;background-color:#0000ff">&lt;</span>` +
+				`<span style="color:#00ff00">&amp;</span>` + "\n" +
+				`<span style="color:#ff0000">&gt; </span></pre>`,
+		},
+		{
+			"classes", ColorModeTrueColor, HTMLOptions{Classes: true, FontFamily: "Fira Mono</style>"},
+			"<style>\n" +
+				".paintbrush{font-family:Fira Mono\\3c /style>}\n" +
+				".pb-fg-00ff00{color:#00ff00}\n" +
+				".pb-fg-ff0000{color:#ff0000}\n" +
+				".pb-bg-0000ff{background-color:#0000ff}\n" +
+				"</style>\n" +
+				`<pre class="paintbrush">` +
+				`<span class="pb-fg-ff0000 pb-bg-0000ff">ab</span>` +
+				`<span class="pb-fg-00ff00 pb-bg-0000ff">&lt;</span>` +
+				`<span class="pb-fg-00ff00">&amp;</span>` + "\n" +
+				`<span class="pb-fg-ff0000">&gt; </span></pre>`,
+		},
+		{
+			"standalone", ColorModeMonochrome, HTMLOptions{Standalone: true, Title: "a<b"},
+			"<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+				"<title>a&lt;b</title>\n" +
+				"</head>\n<body>\n" +
+				`<pre style="font-family:monospace">ab&lt;&amp;` + "\n&gt; </pre>" +
+				"\n</body>\n</html>",
+		},
+		{
+			"monochrome classes", ColorModeMonochrome, HTMLOptions{Classes: true},
+			"<style>\n.paintbrush{font-family:monospace}\n</style>\n" +
+				`<pre class="paintbrush">ab&lt;&amp;` + "\n&gt; </pre>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			n, err := gridResult(t, tt.mode, testGrid).WriteHTML(&sb, tt.opts)
+			if err != nil || n != int64(sb.Len()) {
+				t.Errorf("WriteHTML = %d, %v after writing %d bytes", n, err, sb.Len())
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLReplacesControlCharacters(t *testing.T) {
+	grid := Grid{{{'\t', red, Vec4{}}, {'\x1b', red, Vec4{}}}}
+	want := `<pre style="font-family:monospace"><span style="color:#ff0000">  </span></pre>`
+	if got := gridResult(t, ColorModeTrueColor, grid).HTML(HTMLOptions{}); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -1,45 +1,114 @@
 package paintbrush
 
 import (
-	"fmt"
 	"image"
+	"io/fs"
 	"math"
 	"os"
 )
 
 // LoadImage loads an image from the specified file path.
 func (c *Canvas) LoadImage(path string) error {
-	file, err := os.Open(path)
+	img, err := decodeImageFile(path)
 	if err != nil {
-		fmt.Printf("Error opening image file: %v\n", err)
 		return err
 	}
+	c.SetImage(img)
+	return nil
+}
+
+// decodeImageFile decodes the image file at path. Errors name the path, like
+// those of opening the file.
+func decodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	img, _, err := image.Decode(file)
 	if err != nil {
-		fmt.Printf("Error decoding image: %v\n", err)
-		return err
+		return nil, &fs.PathError{Op: "decode", Path: path, Err: err}
 	}
-
-	c.SetImage(img)
-	return nil
+	return img, nil
 }
 
-// SetImage sets the image to be rendered.
+// SetImage sets the image to be rendered and converts it into the buffer
+// painting samples from. Call SetImage again after modifying the pixels of an
+// image that is already set.
 func (c *Canvas) SetImage(img image.Image) {
 	c.Image = img
+	c.pixels = nil
+	if img != nil {
+		c.pixels = newPixelBuffer(img)
+	}
+}
+
+// sampleImage reads a color for fitting a cell, offset by the dithering bias
+// of the cell and converted to linear light when gamma correction is enabled.
+func (c *Canvas) sampleImage(x, y float64, bias Vec4) Vec4 {
+	col := applyBias(c.readImageColor(x, y), bias)
+	if c.GammaCorrect {
+		col = col.ToLinear()
+	}
+	return col
+}
+
+// quantize snaps a fitted color to the palette, which is defined in sRGB.
+func (c *Canvas) quantize(v Vec4) Vec4 {
+	if !c.GammaCorrect {
+		return c.palette.quantize(v)
+	}
+	return c.palette.quantize(v.ToSRGB()).ToLinear()
+}
+
+// quantizeBg snaps a fitted background color to the palette.
+func (c *Canvas) quantizeBg(v Vec4) Vec4 {
+	if !c.GammaCorrect {
+		return c.palette.quantizeBg(v)
+	}
+	return c.palette.quantizeBg(v.ToSRGB()).ToLinear()
+}
+
+// distance measures the difference between two fitted colors with the
+// color metric.
+func (c *Canvas) distance(a, b Vec4) float64 {
+	return c.Metric.spaceDistance(c.metricSpace(a), c.metricSpace(b))
+}
+
+// metricSpace converts a fitted color into the color space the color metric
+// measures in. The metric expects sRGB input, except that linear colors are
+// already in the space of MetricLinearRGB.
+func (c *Canvas) metricSpace(v Vec4) Vec4 {
+	if c.GammaCorrect {
+		if c.Metric == MetricLinearRGB {
+			return v
+		}
+		v = v.ToSRGB()
+	}
+	return c.Metric.toSpace(v)
+}
+
+// euclideanFit reports whether the color metric is the plain squared distance
+// between fitted colors, which allows errors to be solved in closed form.
+func (c *Canvas) euclideanFit() bool {
+	if c.GammaCorrect {
+		return c.Metric == MetricLinearRGB
+	}
+	return c.Metric == MetricSRGB
 }
 
 func (c *Canvas) readImageColor(x, y float64) Vec4 {
-	if x >= float64(c.Image.Bounds().Dx()) || x < 0 || y >= float64(c.Image.Bounds().Dy()) || y < 0 {
+	if x >= float64(c.pixels.width) || x < 0 || y >= float64(c.pixels.height) || y < 0 {
 		return Vec4{}
 	}
-	r, g, b, a := c.Image.At(int(math.Round(x)), int(math.Round(y))).RGBA()
-	return Vec4{
-		R: float64(r) / 65535.0,
-		G: float64(g) / 65535.0,
-		B: float64(b) / 65535.0,
-		A: float64(a) / 65535.0,
+	if s := c.resampled; s != nil {
+		return s.at(min(int(x/s.scaleX), s.width-1), min(int(y/s.scaleY), s.height-1))
+	}
+	// Samples rounding to the pixel past the edge read nothing
+	px, py := int(math.Round(x)), int(math.Round(y))
+	if px >= c.pixels.width || py >= c.pixels.height {
+		return Vec4{}
 	}
+	return c.pixels.at(px, py)
 }
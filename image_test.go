@@ -0,0 +1,86 @@
+package paintbrush
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+// meanLinearLuminance returns the mean luminance in linear light of RGBA
+// pixels, as a display emits it.
+func meanLinearLuminance(pix []byte) float64 {
+	var sum float64
+	for i := 0; i < len(pix); i += 4 {
+		sum += luminance(srgbToLinear(Vec4{
+			R: float64(pix[i]) / 255,
+			G: float64(pix[i+1]) / 255,
+			B: float64(pix[i+2]) / 255,
+		}))
+	}
+	return sum / float64(len(pix)/4)
+}
+
+// checkerboard returns an image of alternating black and white pixels, which
+// averages to half the light of white.
+func checkerboard(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			i := img.PixOffset(x, y)
+			value := uint8(0)
+			if (x+y)%2 == 0 {
+				value = 255
+			}
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = value, value, value, 255
+		}
+	}
+	return img
+}
+
+// TestGammaCorrectPreservesBrightness compares the light the rendered image
+// emits with the light of the source, which gamma-correct averaging should
+// preserve more closely.
+func TestGammaCorrectPreservesBrightness(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		img  *image.RGBA
+	}{
+		{"checkerboard", checkerboard(280)},
+		{"norman", rgbaOf(loadNorman(t))},
+	} {
+		source := meanLinearLuminance(tt.img.Pix)
+
+		deviation := make(map[bool]float64)
+		for _, gamma := range []bool{false, true} {
+			canvas := New()
+			canvas.SetWidth(40)
+			canvas.SetGammaCorrect(gamma)
+			result, err := canvas.Render(context.Background(), tt.img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deviation[gamma] = math.Abs(meanLinearLuminance(result.RGBABytes()) - source)
+			t.Logf("%s: gamma correct %v: source %.4f, deviation %.4f", tt.name, gamma, source, deviation[gamma])
+		}
+
+		if deviation[true] > 0.01 {
+			t.Errorf("%s: brightness deviates by %.4f with gamma correction", tt.name, deviation[true])
+		}
+		if deviation[true] >= deviation[false] {
+			t.Errorf("%s: brightness deviates by %.4f with gamma correction, %.4f without", tt.name, deviation[true], deviation[false])
+		}
+	}
+}
+
+// rgbaOf copies an image into an RGBA image.
+func rgbaOf(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			img.Set(x, y, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return img
+}
@@ -0,0 +1,211 @@
+package paintbrush
+
+import (
+	"fmt"
+	"math"
+)
+
+// ColorMetric selects the color space in which the difference between two
+// colors is measured, both when choosing glyphs and when quantizing colors to
+// the palette.
+type ColorMetric int
+
+const (
+	MetricSRGB      ColorMetric = iota // Squared distance between gamma-encoded sRGB values
+	MetricLinearRGB                    // Squared distance between linear RGB values
+	MetricCIELAB                       // CIE 1976 ΔE*ab, distance in CIELAB
+	MetricCIEDE2000                    // CIE ΔE 2000
+	MetricOKLab                        // Squared distance in the OKLab color space
+)
+
+// String returns the name of the color metric.
+func (m ColorMetric) String() string {
+	switch m {
+	case MetricSRGB:
+		return "srgb"
+	case MetricLinearRGB:
+		return "linear"
+	case MetricCIELAB:
+		return "cielab"
+	case MetricCIEDE2000:
+		return "ciede2000"
+	case MetricOKLab:
+		return "oklab"
+	}
+	return fmt.Sprintf("ColorMetric(%d)", int(m))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (m ColorMetric) MarshalText() ([]byte, error) {
+	return marshalEnum(m, MetricOKLab)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (m *ColorMetric) UnmarshalText(text []byte) error {
+	return unmarshalEnum(m, MetricOKLab, text)
+}
+
+// distance returns the squared difference between two colors. Alpha is
+// compared directly in every color space, and the color spaces are scaled so
+// that their channels span roughly the same unit range as sRGB.
+func (m ColorMetric) distance(a, b Vec4) float64 {
+	return m.spaceDistance(m.toSpace(a), m.toSpace(b))
+}
+
+// toSpace converts an sRGB color into the color space the metric measures in.
+func (m ColorMetric) toSpace(v Vec4) Vec4 {
+	switch m {
+	case MetricLinearRGB:
+		return srgbToLinear(v)
+	case MetricCIELAB, MetricCIEDE2000:
+		return srgbToLab(v)
+	case MetricOKLab:
+		return srgbToOKLab(v)
+	}
+	return v
+}
+
+// spaceDistance returns the squared difference between two colors that have
+// already been converted with toSpace.
+func (m ColorMetric) spaceDistance(a, b Vec4) float64 {
+	if m == MetricCIEDE2000 {
+		dA := a.A - b.A
+		dE := deltaE2000(a, b)
+		return dE*dE + dA*dA
+	}
+	d := a.Sub(b)
+	return d.Dot(d)
+}
+
+// srgbChannelToLinear removes the sRGB transfer function from a channel.
+func srgbChannelToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearChannelToSRGB applies the sRGB transfer function to a channel.
+func linearChannelToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// srgbToLinear converts a color to linear RGB.
+func srgbToLinear(v Vec4) Vec4 {
+	return Vec4{srgbChannelToLinear(v.R), srgbChannelToLinear(v.G), srgbChannelToLinear(v.B), v.A}
+}
+
+// srgbToLab converts a color to CIELAB under the D65 white point, with all
+// channels divided by 100.
+func srgbToLab(v Vec4) Vec4 {
+	l := srgbToLinear(v)
+	x := (0.4124564*l.R + 0.3575761*l.G + 0.1804375*l.B) / 0.95047
+	y := 0.2126729*l.R + 0.7151522*l.G + 0.0721750*l.B
+	z := (0.0193339*l.R + 0.1191920*l.G + 0.9503041*l.B) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return Vec4{
+		R: (116*fy - 16) / 100,
+		G: 500 * (fx - fy) / 100,
+		B: 200 * (fy - fz) / 100,
+		A: v.A,
+	}
+}
+
+// srgbToOKLab converts a color to OKLab.
+func srgbToOKLab(v Vec4) Vec4 {
+	l := srgbToLinear(v)
+	lc := math.Cbrt(0.4122214708*l.R + 0.5363325363*l.G + 0.0514459929*l.B)
+	mc := math.Cbrt(0.2119034982*l.R + 0.6806995451*l.G + 0.1073969566*l.B)
+	sc := math.Cbrt(0.0883024619*l.R + 0.2817188376*l.G + 0.6299787005*l.B)
+
+	return Vec4{
+		R: 0.2104542553*lc + 0.7936177850*mc - 0.0040720468*sc,
+		G: 1.9779984951*lc - 2.4285922050*mc + 0.4505937099*sc,
+		B: 0.0259040371*lc + 0.7827717662*mc - 0.8086757660*sc,
+		A: v.A,
+	}
+}
+
+// deltaE2000 returns the CIEDE2000 difference between two colors given in
+// the scaled CIELAB space of srgbToLab, divided by 100.
+func deltaE2000(lab1, lab2 Vec4) float64 {
+	l1, a1, b1 := lab1.R*100, lab1.G*100, lab1.B*100
+	l2, a2, b2 := lab2.R*100, lab2.G*100, lab2.B*100
+
+	c1 := math.Hypot(a1, b1)
+	c2 := math.Hypot(a2, b2)
+	cMean7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cMean7/(cMean7+6103515625))) // 25^7
+
+	a1p, a2p := (1+g)*a1, (1+g)*a2
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+
+	hue := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) * 180 / math.Pi
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1p, h2p := hue(b1, a1p), hue(b2, a2p)
+
+	dLp := l2 - l1
+	dCp := c2p - c1p
+	dhp := 0.0
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(dhp*math.Pi/360)
+
+	lMean := (l1 + l2) / 2
+	cMeanP := (c1p + c2p) / 2
+	hMeanP := h1p + h2p
+	if c1p*c2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180:
+			hMeanP /= 2
+		case hMeanP < 360:
+			hMeanP = (hMeanP + 360) / 2
+		default:
+			hMeanP = (hMeanP - 360) / 2
+		}
+	}
+
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	t := 1 - 0.17*math.Cos(rad(hMeanP-30)) + 0.24*math.Cos(rad(2*hMeanP)) +
+		0.32*math.Cos(rad(3*hMeanP+6)) - 0.20*math.Cos(rad(4*hMeanP-63))
+
+	lMean50 := (lMean - 50) * (lMean - 50)
+	sl := 1 + 0.015*lMean50/math.Sqrt(20+lMean50)
+	sc := 1 + 0.045*cMeanP
+	sh := 1 + 0.015*cMeanP*t
+
+	cMeanP7 := math.Pow(cMeanP, 7)
+	rc := 2 * math.Sqrt(cMeanP7/(cMeanP7+6103515625))
+	dTheta := 30 * math.Exp(-((hMeanP-275)/25)*((hMeanP-275)/25))
+	rt := -math.Sin(rad(2*dTheta)) * rc
+
+	dl, dc, dh := dLp/sl, dCp/sc, dHp/sh
+	return math.Sqrt(dl*dl+dc*dc+dh*dh+rt*dc*dh) / 100
+}
@@ -0,0 +1,254 @@
+package paintbrush
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// Option configures a canvas created with NewWithOptions.
+type Option func(*Canvas) error
+
+// NewWithOptions creates a canvas with the default settings of New, then
+// applies the options in order, so later options override earlier ones.
+// Every option validates its settings. The font is rasterized once after all
+// options have been applied, so WithFont and WithFontFile can appear anywhere
+// relative to the glyph dimensions, rune limits, aspect ratio and weights.
+func NewWithOptions(opts ...Option) (*Canvas, error) {
+	c := New()
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.fontData != nil {
+		if err := c.SetFont(c.fontData); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// WithFont sets the font from TrueType data.
+func WithFont(data []byte) Option {
+	return func(c *Canvas) error {
+		if _, err := truetype.Parse(data); err != nil {
+			return fmt.Errorf("%w: %v", ErrFontParse, err)
+		}
+		c.fontData = data
+		return nil
+	}
+}
+
+// WithFontFile sets the font from a TrueType file.
+func WithFontFile(path string) Option {
+	return func(c *Canvas) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return WithFont(data)(c)
+	}
+}
+
+// WithImage sets the image to be rendered.
+func WithImage(img image.Image) Option {
+	return func(c *Canvas) error {
+		if img == nil {
+			return fmt.Errorf("%w: nil image", ErrInvalidOption)
+		}
+		c.SetImage(img)
+		return nil
+	}
+}
+
+// WithSize sets the output size in characters. A zero width or height is
+// derived from the other and the image's aspect ratio.
+func WithSize(width, height int) Option {
+	return func(c *Canvas) error {
+		if width < 0 || height < 0 {
+			return fmt.Errorf("%w: output size %dx%d", ErrInvalidDimensions, width, height)
+		}
+		c.Width, c.Height = width, height
+		return nil
+	}
+}
+
+// WithGlyphDimensions sets the size of a glyph in pixels.
+func WithGlyphDimensions(width, height int) Option {
+	return func(c *Canvas) error {
+		if width <= 0 || height <= 0 {
+			return fmt.Errorf("%w: glyph size %dx%d", ErrInvalidDimensions, width, height)
+		}
+		c.GlyphWidth, c.GlyphHeight = width, height
+		return nil
+	}
+}
+
+// WithAspectRatio sets the aspect ratio correction of the output.
+func WithAspectRatio(ratio float64) Option {
+	return func(c *Canvas) error {
+		if !(ratio > 0) {
+			return fmt.Errorf("%w: aspect ratio %v", ErrInvalidOption, ratio)
+		}
+		c.AspectRatio = ratio
+		return nil
+	}
+}
+
+// WithRuneLimits sets the range of characters rasterized from the font, from
+// start up to but not including end.
+func WithRuneLimits(start, end int) Option {
+	return func(c *Canvas) error {
+		if start < 0 || end <= start {
+			return fmt.Errorf("%w: rune limits %d to %d", ErrInvalidOption, start, end)
+		}
+		c.RuneStart, c.RuneLimit = start, end
+		return nil
+	}
+}
+
+// WithThreads sets the number of rendering workers.
+func WithThreads(threads int) Option {
+	return func(c *Canvas) error {
+		if threads < 1 {
+			return fmt.Errorf("%w: %d threads", ErrInvalidOption, threads)
+		}
+		c.Threads = threads
+		return nil
+	}
+}
+
+// WithForbiddenCharacters excludes characters from rendering.
+func WithForbiddenCharacters(chars ...rune) Option {
+	return func(c *Canvas) error {
+		for _, char := range chars {
+			c.AddForbiddenCharacter(char)
+		}
+		return nil
+	}
+}
+
+// WithWeights adds character weights, rasterizing weighted characters outside
+// the rune limits as well. Weights must be finite.
+func WithWeights(weights map[rune]float64) Option {
+	return func(c *Canvas) error {
+		for char, weight := range weights {
+			if math.IsNaN(weight) || math.IsInf(weight, 0) {
+				return fmt.Errorf("%w: weight %v for %q", ErrInvalidOption, weight, char)
+			}
+		}
+		c.AddWeights(weights)
+		return nil
+	}
+}
+
+// WithColorMode sets the palette used for cell colors and escape codes.
+func WithColorMode(mode ColorMode) Option {
+	return func(c *Canvas) error {
+		if mode < ColorModeTrueColor || mode > ColorModeMonochrome {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, mode)
+		}
+		c.ColorMode = mode
+		return nil
+	}
+}
+
+// WithAutoColorMode detects the color mode from the environment when
+// painting.
+func WithAutoColorMode() Option {
+	return func(c *Canvas) error {
+		c.AutoColorMode = true
+		return nil
+	}
+}
+
+// WithRenderMode sets how each cell is fitted to the image.
+func WithRenderMode(mode RenderMode) Option {
+	return func(c *Canvas) error {
+		if mode < RenderGlyphs || mode > RenderBraille {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, mode)
+		}
+		c.RenderMode = mode
+		return nil
+	}
+}
+
+// WithDither sets how color error is spread between neighbouring cells.
+func WithDither(dither Dither) Option {
+	return func(c *Canvas) error {
+		if dither < DitherNone || dither > DitherBayer {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, dither)
+		}
+		c.Dither = dither
+		return nil
+	}
+}
+
+// WithMetric sets the color space in which color differences are measured.
+func WithMetric(metric ColorMetric) Option {
+	return func(c *Canvas) error {
+		if metric < MetricSRGB || metric > MetricOKLab {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, metric)
+		}
+		c.Metric = metric
+		return nil
+	}
+}
+
+// WithGammaCorrect enables or disables averaging colors in linear light.
+func WithGammaCorrect(enabled bool) Option {
+	return func(c *Canvas) error {
+		c.GammaCorrect = enabled
+		return nil
+	}
+}
+
+// WithResample sets the filter used to sample the image.
+func WithResample(resample Resample) Option {
+	return func(c *Canvas) error {
+		if resample < ResampleNearest || resample > ResampleLanczos {
+			return fmt.Errorf("%w: %v", ErrInvalidOption, resample)
+		}
+		c.Resample = resample
+		return nil
+	}
+}
+
+// WithScorer sets the strategy used to score glyphs for each cell.
+func WithScorer(scorer GlyphScorer) Option {
+	return func(c *Canvas) error {
+		c.Scorer = scorer
+		return nil
+	}
+}
+
+// WithLogger sets the logger that receives warnings.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Canvas) error {
+		c.Logger = logger
+		return nil
+	}
+}
+
+// WithStream sets a writer that receives rows of ANSI text as soon as they
+// are painted.
+func WithStream(w io.Writer) Option {
+	return func(c *Canvas) error {
+		c.Stream = w
+		return nil
+	}
+}
+
+// WithProgress sets a callback called after every rendered cell.
+func WithProgress(fn func(done, total int)) Option {
+	return func(c *Canvas) error {
+		c.OnProgress(fn)
+		return nil
+	}
+}
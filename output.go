@@ -0,0 +1,219 @@
+package paintbrush
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format selects how a result is written out.
+type Format int
+
+const (
+	FormatANSI Format = iota // Text with ANSI escape codes
+	FormatC                  // C-style string declaration
+	FormatBash               // Bash command printing the text
+	FormatHTML               // <pre> element of colored spans
+	FormatSVG                // SVG document of text over background rectangles
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FormatANSI:
+		return "ansi"
+	case FormatC:
+		return "c"
+	case FormatBash:
+		return "bash"
+	case FormatHTML:
+		return "html"
+	case FormatSVG:
+		return "svg"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (f Format) MarshalText() ([]byte, error) {
+	return marshalEnum(f, FormatSVG)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (f *Format) UnmarshalText(text []byte) error {
+	return unmarshalEnum(f, FormatSVG, text)
+}
+
+// extension returns the file name extension for the format.
+func (f Format) extension() string {
+	switch f {
+	case FormatC:
+		return ".h"
+	case FormatBash:
+		return ".sh"
+	case FormatHTML:
+		return ".html"
+	case FormatSVG:
+		return ".svg"
+	}
+	return ".ans"
+}
+
+var (
+	cEscaper    = strings.NewReplacer("\033", "\\033", "\n", "\\n", "\"", "\\\"")
+	bashEscaper = strings.NewReplacer("\\", "\\\\", "\033", "\\e", "\n", "\\n", "'", "\\x27")
+)
+
+// framing returns the text written before and after the ANSI text in the
+// format, and the replacer escaping the ANSI text, if any.
+func (f Format) framing() (prefix, suffix string, escaper *strings.Replacer, err error) {
+	switch f {
+	case FormatANSI:
+		return "", "", nil, nil
+	case FormatC:
+		return "char kCanvas[] = \"", "\"", cEscaper, nil
+	case FormatBash:
+		return "echo -ne '", "'", bashEscaper, nil
+	}
+	return "", "", nil, fmt.Errorf("paintbrush: unknown format %v", f)
+}
+
+// cString declares text as a C string.
+func cString(text string) string {
+	return "char kCanvas[] = \"" + cEscaper.Replace(text) + "\""
+}
+
+// bashString wraps text in a Bash command printing it.
+func bashString(text string) string {
+	return "echo -ne '" + bashEscaper.Replace(text) + "'"
+}
+
+// appendANSIRow appends a row of cells as text with ANSI escape codes,
+// resetting the colors at its end.
+func appendANSIRow(buf []byte, p palette, row []Cell) []byte {
+	lastBg := "\033[0m" // Reset background
+	lastFg := "\033[0m" // Reset foreground
+
+	for _, cell := range row {
+		var newBg string
+		if cell.Bg.A < 0.5 {
+			newBg = "\033[0m"
+		} else {
+			newBg = p.ansiBg(cell.Bg)
+		}
+		if newBg != lastBg {
+			buf = append(buf, newBg...)
+			lastBg = newBg
+		}
+
+		newFg := p.ansiFg(cell.Fg)
+		if newFg != lastFg {
+			buf = append(buf, newFg...)
+			lastFg = newFg
+		}
+		buf = append(buf, string(cell.Rune)...)
+	}
+
+	// Reset colors at the end of each line
+	return append(buf, "\033[0m"...)
+}
+
+// WriteTo writes the result to w as text with ANSI escape codes, one row at a
+// time. It implements io.WriterTo.
+func (r *Result) WriteTo(w io.Writer) (int64, error) {
+	return r.WriteFormat(w, FormatANSI)
+}
+
+// WriteFormat writes the result to w in the given format, one row at a time,
+// without building the whole output in memory. HTML and SVG are written with
+// the default HTMLOptions and SVGOptions.
+func (r *Result) WriteFormat(w io.Writer, format Format) (int64, error) {
+	switch format {
+	case FormatHTML:
+		return r.WriteHTML(w, HTMLOptions{})
+	case FormatSVG:
+		return r.WriteSVG(w, SVGOptions{})
+	}
+	prefix, suffix, escaper, err := format.framing()
+	if err != nil {
+		return 0, err
+	}
+
+	cw := &countingWriter{w: w}
+	io.WriteString(cw, prefix)
+	var row []byte
+	for y := range r.grid {
+		row = appendANSIRow(row[:0], r.palette, r.grid[y])
+		if y < len(r.grid)-1 {
+			row = append(row, '\n')
+		}
+		if escaper != nil {
+			escaper.WriteString(cw, string(row))
+		} else {
+			cw.Write(row)
+		}
+		if cw.err != nil {
+			break
+		}
+	}
+	io.WriteString(cw, suffix)
+	return cw.n, cw.err
+}
+
+// countingWriter counts the bytes written to a writer and keeps the first
+// error, after which nothing more is written.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// rowStreamer writes the rows of a painting as text with ANSI escape codes as
+// soon as they and every row above them are complete.
+type rowStreamer struct {
+	w         io.Writer
+	palette   palette
+	width     int
+	remaining []int // Cells left to render in every row
+	next      int   // First row not yet written
+	buf       []byte
+}
+
+func newRowStreamer(w io.Writer, p palette, width, height int) *rowStreamer {
+	s := &rowStreamer{w: w, palette: p, width: width, remaining: make([]int, height)}
+	for y := range s.remaining {
+		s.remaining[y] = width
+	}
+	return s
+}
+
+// add records a rendered cell and writes every row it completes, given the
+// results of the painting so far in row-major order.
+func (s *rowStreamer) add(result TaskResult, results []TaskResult) error {
+	s.remaining[result.CharY]--
+	for s.next < len(s.remaining) && s.remaining[s.next] == 0 {
+		row := make([]Cell, s.width)
+		for x := range row {
+			row[x] = newCell(results[s.next*s.width+x])
+		}
+		s.buf = appendANSIRow(s.buf[:0], s.palette, row)
+		s.buf = append(s.buf, '\n')
+		if _, err := s.w.Write(s.buf); err != nil {
+			return fmt.Errorf("paintbrush: streaming row %d: %w", s.next, err)
+		}
+		s.next++
+	}
+	return nil
+}
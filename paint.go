@@ -1,16 +1,65 @@
 package paintbrush
 
 import (
+	"context"
+	"fmt"
+	"image"
 	"sort"
 	"sync"
 )
 
-// StartPainting begins the asynchronous painting process.
-func (c *Canvas) StartPainting() {
+// Painting is a handle to a painting running in the background.
+type Painting struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	progress chan ProgressEvent
+	result   *Result
+	err      error
+}
+
+// Cancel stops the painting. Wait returns once the workers have stopped.
+func (p *Painting) Cancel() {
+	p.cancel()
+}
+
+// Done returns a channel that is closed when the painting has finished or
+// stopped after being cancelled.
+func (p *Painting) Done() <-chan struct{} {
+	return p.done
+}
+
+// Progress returns a channel of progress events, which is closed when the
+// painting has ended. Events are not queued: a receiver that falls behind
+// only sees the most recent one.
+func (p *Painting) Progress() <-chan ProgressEvent {
+	return p.progress
+}
+
+// Wait blocks until the painting has finished and returns its result, or the
+// error it stopped with.
+func (p *Painting) Wait() (*Result, error) {
+	<-p.done
+	return p.result, p.err
+}
+
+// StartPainting begins the asynchronous painting process and returns a handle
+// to cancel and wait on it. Progress reaches 1 once the painting has ended,
+// whether it finished or not.
+func (c *Canvas) StartPainting() *Painting {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Painting{
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		progress: make(chan ProgressEvent, 1),
+	}
 	go func() {
-		c.Paint()
-		c.Progress = 1
+		defer close(p.done)
+		defer cancel()
+		p.result, p.err = c.paint(ctx, latestProgress(p.progress))
+		c.resetProgress(1)
+		close(p.progress)
 	}()
+	return p
 }
 
 // GetProgress returns the current progress of the painting process.
@@ -20,98 +69,255 @@ func (c *Canvas) GetProgress() float32 {
 	return c.Progress
 }
 
-func (c *Canvas) renderWorker(wg *sync.WaitGroup, taskChan <-chan Task, resultChan chan<- TaskResult, imgCharWidth, imgCharHeight float64) {
+func (c *Canvas) renderWorker(ctx context.Context, wg *sync.WaitGroup, taskChan <-chan Task, resultChan chan<- TaskResult, imgCharWidth, imgCharHeight float64) {
 	defer wg.Done()
 
 	for task := range taskChan {
-		result := c.processTask(task, imgCharWidth, imgCharHeight)
+		if ctx.Err() != nil {
+			// Drain the remaining tasks without rendering them
+			continue
+		}
+		var result TaskResult
+		switch c.RenderMode {
+		case RenderGlyphs:
+			result = c.processTask(task, imgCharWidth, imgCharHeight)
+		default:
+			result = c.processBlockTask(task, imgCharWidth, imgCharHeight)
+		}
+		if c.Dither.diffuses() {
+			result.Residual = c.residual(result, task.Bias, imgCharWidth, imgCharHeight)
+		}
 		resultChan <- result
 	}
 }
 
 // Paint performs the synchronous painting process.
-func (c *Canvas) Paint() {
-	if len(c.Font.Glyphs) == 0 {
-		fontBytes, err := EmbeddedFonts.ReadFile(FiraMonoRegular)
-		if err != nil {
-			return
-		}
-		err = c.SetFont(fontBytes)
-		if err != nil {
-			return
+func (c *Canvas) Paint() error {
+	_, err := c.PaintContext(context.Background())
+	return err
+}
+
+// PaintContext performs the synchronous painting process and returns its
+// result, which is also stored in the output fields of the canvas. If the
+// context is cancelled, no further cells are rendered and the context's error
+// is returned once every worker has stopped.
+func (c *Canvas) PaintContext(ctx context.Context) (*Result, error) {
+	return c.paint(ctx, nil)
+}
+
+// Render paints an image with the configuration of the canvas and returns
+// the result, leaving the canvas itself untouched. Any number of images can be
+// rendered concurrently with the same canvas, as long as its configuration is
+// not changed meanwhile. Renders do not report progress. Set a font before
+// rendering concurrently so that it is not loaded by every render.
+func (c *Canvas) Render(ctx context.Context, img image.Image) (*Result, error) {
+	r := c.clone()
+	r.SetImage(img)
+	return r.paint(ctx, nil)
+}
+
+// clone returns a canvas with the same configuration and font, without any
+// painting state or output. Maps and the font's glyphs are shared, as
+// painting only reads them.
+func (c *Canvas) clone() *Canvas {
+	return &Canvas{
+		Font:                c.Font,
+		Width:               c.Width,
+		Height:              c.Height,
+		AspectRatio:         c.AspectRatio,
+		GlyphWidth:          c.GlyphWidth,
+		GlyphHeight:         c.GlyphHeight,
+		RuneStart:           c.RuneStart,
+		RuneLimit:           c.RuneLimit,
+		Threads:             c.Threads,
+		ForbiddenCharacters: c.ForbiddenCharacters,
+		Weights:             c.Weights,
+		ColorMode:           c.ColorMode,
+		AutoColorMode:       c.AutoColorMode,
+		RenderMode:          c.RenderMode,
+		Dither:              c.Dither,
+		Metric:              c.Metric,
+		GammaCorrect:        c.GammaCorrect,
+		Resample:            c.Resample,
+		Scorer:              c.Scorer,
+		Logger:              c.Logger,
+		fontData:            c.fontData,
+		fontParams:          c.fontParams,
+	}
+}
+
+// paint performs a painting, passing progress events to observe if it is not
+// nil.
+func (c *Canvas) paint(ctx context.Context, observe func(ProgressEvent)) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.Image == nil {
+		return nil, ErrNoImage
+	}
+	if c.Image.Bounds().Empty() {
+		return nil, fmt.Errorf("%w: image is empty", ErrInvalidDimensions)
+	}
+	if c.Width < 0 || c.Height < 0 {
+		return nil, fmt.Errorf("%w: output size %dx%d", ErrInvalidDimensions, c.Width, c.Height)
+	}
+	if c.GlyphWidth <= 0 || c.GlyphHeight <= 0 {
+		return nil, fmt.Errorf("%w: glyph size %dx%d", ErrInvalidDimensions, c.GlyphWidth, c.GlyphHeight)
+	}
+
+	if err := c.prepareFont(); err != nil {
+		return nil, err
+	}
+
+	if !c.pixels.converts(c.Image) {
+		c.pixels = newPixelBuffer(c.Image)
+	}
+
+	c.palette = palette{mode: c.ColorMode, metric: c.Metric}
+	if c.AutoColorMode {
+		c.palette.mode = DetectColorMode()
+	}
+
+	if c.RenderMode == RenderGlyphs {
+		c.prepareCandidates()
+		if len(c.candidates) == 0 {
+			return nil, ErrNoGlyphs
 		}
+	} else {
+		c.buildBlockGlyphs()
 	}
 
+	c.resetProgress(0)
 	c.Result = ""
 	c.ResultRGBABytes = nil
 	c.ResultC = ""
 	c.ResultBash = ""
 
 	width, height := c.calculateDimensions(c.Image.Bounds().Dx(), c.Image.Bounds().Dx())
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%w: output size %dx%d", ErrInvalidDimensions, width, height)
+	}
 
 	imgCharWidth := float64(int((float64(c.Image.Bounds().Dx())/float64(width))*float64(c.GlyphWidth))) / float64(c.GlyphWidth)
 	imgCharHeight := float64(c.Image.Bounds().Dy()) / float64(height)
 
-	c.ResultRGBAWidth = width * c.Font.GlyphWidth
-	c.ResultRGBAHeight = height * c.Font.GlyphHeight
-	c.ResultRGBABytes = make([]byte, c.ResultRGBAWidth*c.ResultRGBAHeight*4)
+	c.resampled = nil
+	if c.Resample != ResampleNearest {
+		c.resampled = c.pixels.resample(
+			width*c.Font.GlyphWidth, height*c.Font.GlyphHeight,
+			imgCharWidth/float64(c.Font.GlyphWidth), imgCharHeight/float64(c.Font.GlyphHeight),
+			c.Resample, c.GammaCorrect, c.Threads,
+		)
+	}
 
 	tasks := make([]Task, 0, width*height)
 	for charY := 0; charY < height; charY++ {
 		for charX := 0; charX < width; charX++ {
-			tasks = append(tasks, Task{CharX: charX, CharY: charY})
+			task := Task{CharX: charX, CharY: charY}
+			if c.Dither == DitherBayer {
+				task.Bias = c.orderedBias(charX, charY)
+			}
+			tasks = append(tasks, task)
 		}
 	}
 
-	sort.Slice(tasks, func(i, j int) bool {
-		distFunc := func(t Task) float64 {
-			dx := float64(t.CharX) - float64(width)/2
-			dy := float64(t.CharY) - float64(height)/2
-			return dx*dx/c.Font.Aspect + dy*dy*c.Font.Aspect
-		}
-		di, dj := distFunc(tasks[i]), distFunc(tasks[j])
-		if di == dj {
-			if tasks[i].CharX == tasks[j].CharX {
-				return tasks[i].CharY < tasks[j].CharY
+	// Streamed rows are written top to bottom, so they are rendered in the
+	// order the tasks were created
+	if c.Stream == nil {
+		sort.Slice(tasks, func(i, j int) bool {
+			distFunc := func(t Task) float64 {
+				dx := float64(t.CharX) - float64(width)/2
+				dy := float64(t.CharY) - float64(height)/2
+				return dx*dx/c.Font.Aspect + dy*dy*c.Font.Aspect
 			}
-			return tasks[i].CharX < tasks[j].CharX
-		}
-		return di > dj
-	})
+			di, dj := distFunc(tasks[i]), distFunc(tasks[j])
+			if di == dj {
+				if tasks[i].CharX == tasks[j].CharX {
+					return tasks[i].CharY < tasks[j].CharY
+				}
+				return tasks[i].CharX < tasks[j].CharX
+			}
+			return di > dj
+		})
+	}
+
+	// Workers stop early when collecting a result fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	taskResults := make([]TaskResult, len(tasks))
+	var stream *rowStreamer
+	if c.Stream != nil {
+		stream = newRowStreamer(c.Stream, c.palette, width, height)
+	}
+	done := 0
+	collect := func(result TaskResult) error {
+		taskResults[result.CharY*width+result.CharX] = result
+		done++
+		c.setProgress(done, len(tasks), observe)
+		if stream != nil {
+			return stream.add(result, taskResults)
+		}
+		return nil
+	}
+
 	var wg sync.WaitGroup
 	taskChan := make(chan Task, len(tasks))
 	resultChan := make(chan TaskResult, len(tasks))
 
 	// Start worker goroutines
-	for i := 0; i < c.Threads; i++ {
+	for i := 0; i < max(c.Threads, 1); i++ {
 		wg.Add(1)
-		go c.renderWorker(&wg, taskChan, resultChan, imgCharWidth, imgCharHeight)
+		go c.renderWorker(ctx, &wg, taskChan, resultChan, imgCharWidth, imgCharHeight)
 	}
 
-	// Feed tasks to workers
-	go func() {
-		for _, task := range tasks {
-			taskChan <- task
-		}
+	var err error
+	if c.Dither.diffuses() {
+		// Error diffusion needs neighbours solved first, one wavefront at a time
+		err = c.paintWavefront(ctx, taskChan, resultChan, taskResults, width, height, collect)
 		close(taskChan)
-	}()
+	} else {
+		// Feed tasks to workers
+		go func() {
+			defer close(taskChan)
+			for _, task := range tasks {
+				select {
+				case taskChan <- task:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
 
-	// Collect results
-	go func() {
+		// Collect results
+	collect:
 		for i := 0; i < len(tasks); i++ {
-			result := <-resultChan
-			taskResults[result.CharY*width+result.CharX] = result
-			c.Progress = float32(i+1) / float32(len(tasks))
+			select {
+			case result := <-resultChan:
+				if err = collect(result); err != nil {
+					break collect
+				}
+			case <-ctx.Done():
+				err = ctx.Err()
+				break collect
+			}
 		}
-		close(resultChan)
-	}()
+	}
 
+	cancel()
 	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
 
 	// Process results
-	c.processResults(taskResults, width, height)
+	result := c.newResult(taskResults, width, height)
+	c.Result = result.Text()
+	c.ResultC = cString(c.Result)
+	c.ResultBash = bashString(c.Result)
+	c.ResultRGBABytes = result.RGBABytes()
+	c.ResultRGBAWidth, c.ResultRGBAHeight = result.RGBADimensions()
+	return result, nil
 }
 
 func (c *Canvas) calculateDimensions(imageX, imageY int) (width int, height int) {
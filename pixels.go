@@ -0,0 +1,118 @@
+package paintbrush
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+)
+
+// pixelBuffer is a copy of the source image with premultiplied 16-bit
+// channels, holding exactly the values color.Color.RGBA reports. Workers
+// sample it directly instead of calling image.Image.At for every sample.
+type pixelBuffer struct {
+	source         image.Image
+	width, height  int
+	scaleX, scaleY float64  // Source pixels covered by each pixel, zero for the source itself
+	pix            []uint16 // RGBA, row-major, starting at the image's origin
+}
+
+// newPixelBuffer converts an image, with fast paths for the concrete image
+// types produced by the standard decoders.
+func newPixelBuffer(img image.Image) *pixelBuffer {
+	bounds := img.Bounds()
+	b := &pixelBuffer{
+		source: img,
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		pix:    make([]uint16, bounds.Dx()*bounds.Dy()*4),
+	}
+
+	i := 0
+	switch src := img.(type) {
+	case *image.RGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < b.width*4; x++ {
+				b.pix[i] = uint16(row[x]) * 0x101
+				i++
+			}
+		}
+	case *image.NRGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < b.width*4; x += 4 {
+				a := uint32(row[x+3]) * 0x101
+				b.pix[i] = uint16(uint32(row[x]) * 0x101 * a / 0xffff)
+				b.pix[i+1] = uint16(uint32(row[x+1]) * 0x101 * a / 0xffff)
+				b.pix[i+2] = uint16(uint32(row[x+2]) * 0x101 * a / 0xffff)
+				b.pix[i+3] = uint16(a)
+				i += 4
+			}
+		}
+	case *image.YCbCr:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				yi, ci := src.YOffset(x, y), src.COffset(x, y)
+				r, g, bl, _ := color.YCbCr{Y: src.Y[yi], Cb: src.Cb[ci], Cr: src.Cr[ci]}.RGBA()
+				b.pix[i], b.pix[i+1], b.pix[i+2], b.pix[i+3] = uint16(r), uint16(g), uint16(bl), 0xffff
+				i += 4
+			}
+		}
+	case *image.Paletted:
+		palette := make([][4]uint16, len(src.Palette))
+		for j, col := range src.Palette {
+			r, g, bl, a := col.RGBA()
+			palette[j] = [4]uint16{uint16(r), uint16(g), uint16(bl), uint16(a)}
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < b.width; x++ {
+				if int(row[x]) < len(palette) {
+					copy(b.pix[i:i+4], palette[row[x]][:])
+				}
+				i += 4
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < b.width; x++ {
+				gray := uint16(row[x]) * 0x101
+				b.pix[i], b.pix[i+1], b.pix[i+2], b.pix[i+3] = gray, gray, gray, 0xffff
+				i += 4
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, bl, a := img.At(x, y).RGBA()
+				b.pix[i], b.pix[i+1], b.pix[i+2], b.pix[i+3] = uint16(r), uint16(g), uint16(bl), uint16(a)
+				i += 4
+			}
+		}
+	}
+
+	return b
+}
+
+// at returns the normalized color of the pixel at the given offset from the
+// image's origin.
+func (b *pixelBuffer) at(x, y int) Vec4 {
+	i := (y*b.width + x) * 4
+	return Vec4{
+		R: float64(b.pix[i]) / 65535.0,
+		G: float64(b.pix[i+1]) / 65535.0,
+		B: float64(b.pix[i+2]) / 65535.0,
+		A: float64(b.pix[i+3]) / 65535.0,
+	}
+}
+
+// converts reports whether the buffer was converted from the given image.
+// Images of types that cannot be compared are always converted again.
+func (b *pixelBuffer) converts(img image.Image) bool {
+	if b == nil || img == nil {
+		return false
+	}
+	t := reflect.TypeOf(img)
+	return t == reflect.TypeOf(b.source) && t.Comparable() && img == b.source
+}
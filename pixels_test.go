@@ -0,0 +1,128 @@
+package paintbrush
+
+import (
+	"bytes"
+	"image"
+	stdpalette "image/color/palette"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+)
+
+// namedImage is a test image along with the name of its type.
+type namedImage struct {
+	name string
+	img  image.Image
+}
+
+// normanAs returns the example image converted to the image types the
+// standard decoders produce.
+func normanAs(tb testing.TB) []namedImage {
+	tb.Helper()
+	src := loadNorman(tb)
+	bounds := src.Bounds()
+
+	nrgba := image.NewNRGBA(bounds)
+	draw.Draw(nrgba, bounds, src, bounds.Min, draw.Src)
+
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, src, bounds.Min, draw.Src)
+
+	paletted := image.NewPaletted(bounds, stdpalette.Plan9)
+	draw.Draw(paletted, bounds, src, bounds.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		tb.Fatal(err)
+	}
+	ycbcr, err := jpeg.Decode(&buf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, ok := ycbcr.(*image.YCbCr); !ok {
+		tb.Fatalf("decoded JPEG is %T, want *image.YCbCr", ycbcr)
+	}
+
+	return []namedImage{
+		{"NRGBA", nrgba},
+		{"RGBA", rgba},
+		{"YCbCr", ycbcr},
+		{"Paletted", paletted},
+	}
+}
+
+func TestPixelBufferMatchesAt(t *testing.T) {
+	for _, tt := range normanAs(t) {
+		name, img := tt.name, tt.img
+		b := newPixelBuffer(img)
+		bounds := img.Bounds()
+		for y := 0; y < b.height; y++ {
+			for x := 0; x < b.width; x++ {
+				r, g, bl, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				want := Vec4{float64(r) / 65535, float64(g) / 65535, float64(bl) / 65535, float64(a) / 65535}
+				if got := b.at(x, y); got != want {
+					t.Fatalf("%s: pixel %d,%d is %v, want %v", name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+// samplePoints returns the points a 150 column painting samples, one per
+// glyph pixel of every cell.
+func samplePoints(img image.Image) []image.Point {
+	const width, glyphWidth, glyphHeight = 150, 7, 14
+	bounds := img.Bounds()
+	cellWidth := float64(bounds.Dx()) / width
+	height := int(float64(bounds.Dy()) / cellWidth / 2)
+	cellHeight := float64(bounds.Dy()) / float64(height)
+
+	var points []image.Point
+	for cy := 0; cy < height; cy++ {
+		for cx := 0; cx < width; cx++ {
+			for gy := 0; gy < glyphHeight; gy++ {
+				for gx := 0; gx < glyphWidth; gx++ {
+					x := int((float64(cx) + (float64(gx)+0.5)/glyphWidth) * cellWidth)
+					y := int((float64(cy) + (float64(gy)+0.5)/glyphHeight) * cellHeight)
+					points = append(points, image.Pt(x, y))
+				}
+			}
+		}
+	}
+	return points
+}
+
+// BenchmarkSampleAt reads every sample of a painting through image.Image.At.
+func BenchmarkSampleAt(b *testing.B) {
+	for _, tt := range normanAs(b) {
+		img := tt.img
+		points := samplePoints(img)
+		b.Run(tt.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var sum uint32
+				for _, p := range points {
+					r, _, _, _ := img.At(p.X, p.Y).RGBA()
+					sum += r
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSamplePixelBuffer converts the image and reads every sample of a
+// painting from the buffer.
+func BenchmarkSamplePixelBuffer(b *testing.B) {
+	for _, tt := range normanAs(b) {
+		img := tt.img
+		points := samplePoints(img)
+		b.Run(tt.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				buffer := newPixelBuffer(img)
+				var sum float64
+				for _, p := range points {
+					sum += buffer.at(p.X, p.Y).R
+				}
+			}
+		})
+	}
+}
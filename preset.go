@@ -0,0 +1,258 @@
+package paintbrush
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed presets/*.json
+var EmbeddedPresets embed.FS
+
+// Preset is a named look for a canvas that can be shared as JSON. Only the
+// settings a preset specifies are applied, so presets can also adjust a
+// single aspect of another configuration. Zero numbers, missing modes and
+// missing weights and forbidden characters are left unspecified, so a preset
+// giving only the glyph width or only the rune limit keeps the other value of
+// the canvas. Empty weights or forbidden characters clear those of the
+// canvas.
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	GlyphWidth  int                `json:"glyph_width,omitempty"`
+	GlyphHeight int                `json:"glyph_height,omitempty"`
+	AspectRatio float64            `json:"aspect_ratio,omitempty"`
+	RuneStart   int                `json:"rune_start,omitempty"`
+	RuneLimit   int                `json:"rune_limit,omitempty"`
+	Weights     map[string]float64 `json:"weights"`             // Weights keyed by single characters, unspecified when nil
+	Forbidden   *string            `json:"forbidden,omitempty"` // Every character of the string is forbidden
+
+	ColorMode    *ColorMode   `json:"color_mode,omitempty"`
+	RenderMode   *RenderMode  `json:"render_mode,omitempty"`
+	Dither       *Dither      `json:"dither,omitempty"`
+	Metric       *ColorMetric `json:"metric,omitempty"`
+	GammaCorrect *bool        `json:"gamma_correct,omitempty"`
+	Resample     *Resample    `json:"resample,omitempty"`
+}
+
+// Preset captures the current look of the canvas as a preset with the given
+// name, specifying every setting. Forbidden characters are sorted, and empty
+// weights and forbidden characters are kept, so that applying the preset
+// clears those of another canvas.
+func (c *Canvas) Preset(name string) Preset {
+	forbidden := c.GetForbiddenCharacters()
+	sort.Slice(forbidden, func(i, j int) bool { return forbidden[i] < forbidden[j] })
+	p := Preset{
+		Name:        name,
+		GlyphWidth:  c.GlyphWidth,
+		GlyphHeight: c.GlyphHeight,
+		AspectRatio: c.AspectRatio,
+		RuneStart:   c.RuneStart,
+		RuneLimit:   c.RuneLimit,
+		Weights:     make(map[string]float64, len(c.Weights)),
+	}
+	chars := string(forbidden)
+	p.Forbidden = &chars
+	for char, weight := range c.Weights {
+		p.Weights[string(char)] = weight
+	}
+
+	// Point at copies rather than the canvas's fields
+	colorMode, renderMode, dither, metric, gamma, resample := c.ColorMode, c.RenderMode, c.Dither, c.Metric, c.GammaCorrect, c.Resample
+	p.ColorMode, p.RenderMode, p.Dither, p.Metric, p.GammaCorrect, p.Resample = &colorMode, &renderMode, &dither, &metric, &gamma, &resample
+	return p
+}
+
+// ApplyPreset applies the settings a preset specifies, validating them like
+// the options of NewWithOptions. Weights and forbidden characters replace
+// those of the canvas. Nothing is applied if any setting is invalid.
+func (c *Canvas) ApplyPreset(p Preset) error {
+	opts, err := p.options()
+	if err != nil {
+		return err
+	}
+
+	// Validate on a copy first, so that a failing preset leaves no trace
+	check := c.clone()
+	check.ForbiddenCharacters = make(map[rune]struct{})
+	check.Weights = make(map[rune]float64)
+	for _, opt := range opts {
+		if err := opt(check); err != nil {
+			return fmt.Errorf("preset %q: %w", p.Name, err)
+		}
+	}
+
+	if p.Weights != nil {
+		c.Weights = make(map[rune]float64)
+	}
+	if p.Forbidden != nil {
+		c.ClearForbiddenCharacters()
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return nil
+}
+
+// WithPreset applies the settings a preset specifies.
+func WithPreset(p Preset) Option {
+	return func(c *Canvas) error {
+		return c.ApplyPreset(p)
+	}
+}
+
+// options converts the settings a preset specifies into options.
+func (p Preset) options() ([]Option, error) {
+	var opts []Option
+	if p.GlyphWidth != 0 || p.GlyphHeight != 0 {
+		opts = append(opts, func(c *Canvas) error {
+			return WithGlyphDimensions(specified(p.GlyphWidth, c.GlyphWidth), specified(p.GlyphHeight, c.GlyphHeight))(c)
+		})
+	}
+	if p.AspectRatio != 0 {
+		opts = append(opts, WithAspectRatio(p.AspectRatio))
+	}
+	if p.RuneStart != 0 || p.RuneLimit != 0 {
+		opts = append(opts, func(c *Canvas) error {
+			return WithRuneLimits(specified(p.RuneStart, c.RuneStart), specified(p.RuneLimit, c.RuneLimit))(c)
+		})
+	}
+	if p.Weights != nil {
+		weights := make(map[rune]float64, len(p.Weights))
+		for key, weight := range p.Weights {
+			char, size := utf8.DecodeRuneInString(key)
+			if size == 0 || size != len(key) {
+				return nil, fmt.Errorf("preset %q: %w: weight key %q is not a single character", p.Name, ErrInvalidOption, key)
+			}
+			weights[char] = weight
+		}
+		opts = append(opts, WithWeights(weights))
+	}
+	if p.Forbidden != nil {
+		opts = append(opts, WithForbiddenCharacters([]rune(*p.Forbidden)...))
+	}
+	if p.ColorMode != nil {
+		opts = append(opts, WithColorMode(*p.ColorMode))
+	}
+	if p.RenderMode != nil {
+		opts = append(opts, WithRenderMode(*p.RenderMode))
+	}
+	if p.Dither != nil {
+		opts = append(opts, WithDither(*p.Dither))
+	}
+	if p.Metric != nil {
+		opts = append(opts, WithMetric(*p.Metric))
+	}
+	if p.GammaCorrect != nil {
+		opts = append(opts, WithGammaCorrect(*p.GammaCorrect))
+	}
+	if p.Resample != nil {
+		opts = append(opts, WithResample(*p.Resample))
+	}
+	return opts, nil
+}
+
+// specified returns the setting of a preset, or the current value of the
+// canvas if the preset leaves it unspecified.
+func specified(setting, current int) int {
+	if setting == 0 {
+		return current
+	}
+	return setting
+}
+
+// ParsePreset decodes a preset from JSON. Unknown fields are rejected, so
+// that misspelled settings do not go unnoticed, and the settings are
+// validated by applying them to a canvas created with New.
+func ParsePreset(data []byte) (Preset, error) {
+	var p Preset
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		if errors.Is(err, ErrInvalidOption) {
+			return Preset{}, err
+		}
+		return Preset{}, fmt.Errorf("%w: %v", ErrInvalidPreset, err)
+	}
+	if err := New().ApplyPreset(p); err != nil {
+		return Preset{}, err
+	}
+	return p, nil
+}
+
+// LoadPreset reads a preset from a JSON file.
+func LoadPreset(path string) (Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Preset{}, err
+	}
+	p, err := ParsePreset(data)
+	if err != nil {
+		return Preset{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// SavePreset writes a preset to a JSON file.
+func SavePreset(path string, p Preset) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// BuiltinPreset returns one of the presets embedded in the package.
+func BuiltinPreset(name string) (Preset, error) {
+	data, err := EmbeddedPresets.ReadFile(path.Join("presets", name+".json"))
+	if err != nil {
+		return Preset{}, fmt.Errorf("%w: %q", ErrUnknownPreset, name)
+	}
+	return ParsePreset(data)
+}
+
+// BuiltinPresets returns the names of the presets embedded in the package,
+// in alphabetical order.
+func BuiltinPresets() []string {
+	entries, _ := EmbeddedPresets.ReadDir("presets")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// marshalEnum returns the name of a mode for encoding, rejecting values
+// beyond the last defined one.
+func marshalEnum[T interface {
+	~int
+	fmt.Stringer
+}](v, last T) ([]byte, error) {
+	if v < 0 || v > last {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOption, v)
+	}
+	return []byte(v.String()), nil
+}
+
+// unmarshalEnum decodes the name of a mode.
+func unmarshalEnum[T interface {
+	~int
+	fmt.Stringer
+}](v *T, last T, text []byte) error {
+	for candidate := T(0); candidate <= last; candidate++ {
+		if candidate.String() == string(text) {
+			*v = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown %T %q", ErrInvalidOption, *v, text)
+}
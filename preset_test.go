@@ -0,0 +1,83 @@
+package paintbrush
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParsePresetValidates(t *testing.T) {
+	for _, data := range []string{
+		`{"name":"negative","glyph_width":-8}`,
+		`{"name":"empty range","rune_start":100,"rune_limit":100}`,
+		`{"name":"below start","rune_limit":20}`,
+		`{"name":"ratio","aspect_ratio":-1}`,
+	} {
+		if _, err := ParsePreset([]byte(data)); !errors.Is(err, ErrInvalidDimensions) && !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("ParsePreset(%s) = %v, want an invalid setting", data, err)
+		}
+	}
+}
+
+func TestPresetAppliesFieldsOnTheirOwn(t *testing.T) {
+	p, err := ParsePreset([]byte(`{"name":"partial","glyph_width":8,"rune_limit":200}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canvas := New()
+	canvas.SetGlyphDimensions(6, 12)
+	canvas.SetRuneLimits(40, 100)
+	if err := canvas.ApplyPreset(p); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := canvas.GetGlyphDimensions(); w != 8 || h != 12 {
+		t.Errorf("glyph size %dx%d, want 8x12", w, h)
+	}
+	if start, limit := canvas.GetRuneLimits(); start != 40 || limit != 200 {
+		t.Errorf("rune limits %d to %d, want 40 to 200", start, limit)
+	}
+}
+
+func TestPresetOfCleanCanvasClearsSets(t *testing.T) {
+	p, err := ParsePreset(mustMarshal(t, New().Preset("clean")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canvas := New()
+	canvas.AddForbiddenCharacter('A')
+	canvas.SetWeights(map[rune]float64{'#': 2})
+	if err := canvas.ApplyPreset(p); err != nil {
+		t.Fatal(err)
+	}
+	if canvas.IsForbiddenCharacter('A') {
+		t.Error("forbidden character survived a preset without forbidden characters")
+	}
+	if len(canvas.Weights) != 0 {
+		t.Errorf("weights %v survived a preset without weights", canvas.Weights)
+	}
+}
+
+func TestPresetIsDeterministic(t *testing.T) {
+	canvas := New()
+	for _, char := range "zyxwvutsrqponmlkjihgfedcba" {
+		canvas.AddForbiddenCharacter(char)
+	}
+	want := string(mustMarshal(t, canvas.Preset("sorted")))
+	for i := 0; i < 10; i++ {
+		if got := string(mustMarshal(t, canvas.Preset("sorted"))); got != want {
+			t.Fatalf("preset changed between calls:\n%s\n%s", want, got)
+		}
+	}
+	if p := canvas.Preset("sorted"); *p.Forbidden != "abcdefghijklmnopqrstuvwxyz" {
+		t.Errorf("forbidden %q, want it sorted", *p.Forbidden)
+	}
+}
+
+func mustMarshal(tb testing.TB, p Preset) []byte {
+	tb.Helper()
+	data, err := json.Marshal(p)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
@@ -1,133 +1,104 @@
 package paintbrush
 
 import (
-	"fmt"
 	"math"
+	"sort"
 )
 
 type Task struct {
 	CharX, CharY int
+	Bias         Vec4 // Dithering offset added to every sample of the cell
 }
 
 type TaskResult struct {
 	CharX, CharY int
 	Fg, Bg       Vec4
 	Glyph        *Glyph
+	Residual     Vec4 // Mean color error left over for error diffusion
 }
 
-func (c *Canvas) processTask(task Task, imgCharWidth, imgCharHeight float64) TaskResult {
-	imgXBegin := float64(task.CharX) * imgCharWidth
-	imgYBegin := float64(task.CharY) * imgCharHeight
+// glyphCandidate is a glyph eligible for selection along with its weight.
+type glyphCandidate struct {
+	glyph  *Glyph
+	weight float64
+}
 
-	bestGlyph := c.Font.Glyphs[' ']
-	bestErr := math.MaxFloat64
-	var bestFg, bestBg Vec4
+// prepareCandidates collects the glyphs eligible for selection and makes
+// sure their coverage data is precomputed. Candidates are kept in rune order
+// so that ties between glyphs are always broken the same way.
+func (c *Canvas) prepareCandidates() {
+	runes := make([]rune, 0, len(c.Font.Glyphs))
+	for r := range c.Font.Glyphs {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
 
-	for _, glyph := range c.Font.Glyphs {
-		if c.IsForbiddenCharacter(rune(glyph.Unicode)) {
+	size := c.Font.GlyphWidth * c.Font.GlyphHeight
+	c.candidates = c.candidates[:0]
+	for _, r := range runes {
+		glyph := c.Font.Glyphs[r]
+		if c.IsForbiddenCharacter(r) {
 			continue
 		}
+		if len(glyph.Pixels) < size {
+			c.logger().Warn("skipping glyph with too few pixels", "rune", glyph.UTF8, "pixels", len(glyph.Pixels), "expected", size)
+			continue
+		}
+		weight := c.GetWeight(r)
+		if !(weight > 0) || math.IsInf(weight, 1) {
+			continue
+		}
+		glyph.fit = glyph.fitData(c.Font.GlyphWidth)
+		c.candidates = append(c.candidates, glyphCandidate{glyph: &glyph, weight: weight})
+	}
+}
 
-		fgSum := 0.0
-		fgCol := Vec4{}
-		bgSum := 0.0
-		bgCol := Vec4{}
-
-		for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
-			for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
-				index := fontCharX + fontCharY*c.Font.GlyphWidth
-				if index >= len(glyph.Pixels) {
-					fmt.Printf("Warning: Index out of range for glyph '%s'. Index: %d, Pixel array length: %d\n", glyph.UTF8, index, len(glyph.Pixels))
-					continue
-				}
-				fg := float64(glyph.Pixels[index]) / 255.0
-				bg := 1.0 - fg
-				fgSum += fg
-				bgSum += bg
-
-				imgX := imgXBegin + imgCharWidth*(float64(fontCharX)+0.5)/float64(c.Font.GlyphWidth)
-				imgY := imgYBegin + imgCharHeight*(float64(fontCharY)+0.5)/float64(c.Font.GlyphHeight)
-				col := c.readImageColor(imgX, imgY)
+// tieTolerance is the error per sample within which glyphs count as equally
+// good. It lies far above the rounding noise of the scorers and far below any
+// difference that is visible.
+const tieTolerance = 1e-9
 
-				fgCol = fgCol.Add(col.Mul(fg))
-				bgCol = bgCol.Add(col.Mul(bg))
-			}
-		}
+// processTask finds the glyph that best renders a cell according to the
+// canvas's scorer. The scorer returns the raw error of each glyph, which is
+// divided by the glyph's weight here and nowhere else. Glyphs whose errors
+// are equal within tieTolerance are tied, and the lowest rune wins the tie,
+// so that a flat cell always renders as the same glyph.
+func (c *Canvas) processTask(task Task, imgCharWidth, imgCharHeight float64) TaskResult {
+	cell := c.sampleCell(task, imgCharWidth, imgCharHeight)
 
-		if fgSum > 0 {
-			fgCol = fgCol.Div(fgSum)
-		}
-		fgCol.A = 1
+	var scorer GlyphScorer = ColorScorer{}
+	if c.Scorer != nil {
+		scorer = c.Scorer
+	}
 
-		if bgSum > 0 {
-			bgCol = bgCol.Div(bgSum)
-		}
-		if bgCol.A < 0.2 {
-			bgCol.A = 0
-		} else {
-			bgCol.A = 1
-		}
-		bgCol = bgCol.Mul(bgCol.A) // premultiply
+	// Candidates are in rune order, so a later glyph must be better by more
+	// than the tolerance to replace an earlier one
+	tolerance := tieTolerance * float64(len(cell.Pixels))
+	var bestGlyph *Glyph
+	bestErr := math.Inf(1)
+	var bestFg, bestBg Vec4
 
-		error := c.calculateError(&glyph, fgCol, bgCol, imgXBegin, imgYBegin, imgCharWidth, imgCharHeight)
-		error /= glyph.Weight
+	for _, candidate := range c.candidates {
+		fg, bg, error := scorer.Score(candidate.glyph, cell)
+		error /= candidate.weight
 
-		if error < bestErr {
+		if bestGlyph == nil || error < bestErr-tolerance {
 			bestErr = error
-			bestGlyph = glyph
-			bestFg = fgCol
-			bestBg = bgCol
+			bestGlyph = candidate.glyph
+			bestFg = fg
+			bestBg = bg
 		}
 	}
 
-	// Blit the character onto resultRGBABytes
-	c.blitCharacter(task.CharX, task.CharY, &bestGlyph, bestFg, bestBg)
+	if c.GammaCorrect {
+		bestFg, bestBg = bestFg.ToSRGB(), bestBg.ToSRGB()
+	}
 
 	return TaskResult{
 		CharX: task.CharX,
 		CharY: task.CharY,
 		Fg:    bestFg,
 		Bg:    bestBg,
-		Glyph: &bestGlyph,
-	}
-}
-
-func (c *Canvas) calculateError(glyph *Glyph, fgCol, bgCol Vec4, imgXBegin, imgYBegin, imgCharWidth, imgCharHeight float64) float64 {
-	error := 0.0
-	for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
-		for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
-			fg := float64(glyph.Pixels[fontCharX+fontCharY*c.Font.GlyphWidth]) / 255.0
-			bg := 1.0 - fg
-			imgX := imgXBegin + imgCharWidth*(float64(fontCharX)+0.5)/float64(c.Font.GlyphWidth)
-			imgY := imgYBegin + imgCharHeight*(float64(fontCharY)+0.5)/float64(c.Font.GlyphHeight)
-			col := c.readImageColor(imgX, imgY)
-			col = col.Mul(col.A) // premultiply
-			x := fgCol.Mul(fg).Add(bgCol.Mul(bg))
-			d := col.Sub(x)
-			error += d.Dot(d)
-		}
-	}
-
-	if weight, exists := c.Weights[rune(glyph.Unicode)]; exists {
-		error /= weight
-	}
-
-	return error
-}
-
-func (c *Canvas) blitCharacter(charX, charY int, glyph *Glyph, fg, bg Vec4) {
-	for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
-		for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
-			resultX := charX*c.Font.GlyphWidth + fontCharX
-			resultY := charY*c.Font.GlyphHeight + fontCharY
-			idx := (resultY*c.ResultRGBAWidth + resultX) * 4
-			fgFactor := float64(glyph.Pixels[fontCharX+fontCharY*c.Font.GlyphWidth]) / 255.0
-			bgFactor := 1.0 - fgFactor
-			pixel := fg.Mul(fgFactor).Add(bg.Mul(bgFactor))
-			c.ResultRGBABytes[idx] = uint8(pixel.R * 255)
-			c.ResultRGBABytes[idx+1] = uint8(pixel.G * 255)
-			c.ResultRGBABytes[idx+2] = uint8(pixel.B * 255)
-			c.ResultRGBABytes[idx+3] = uint8(pixel.A * 255)
-		}
+		Glyph: bestGlyph,
 	}
 }
@@ -0,0 +1,74 @@
+package paintbrush
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestNonFiniteWeightsAreNeverChosen(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for _, weight := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 0, -1} {
+		for _, dither := range []Dither{DitherNone, DitherFloydSteinberg} {
+			canvas := New()
+			canvas.SetWidth(4)
+			canvas.SetRuneLimits('A', 'B')
+			canvas.SetWeights(map[rune]float64{'A': weight})
+			canvas.SetDither(dither)
+			if _, err := canvas.Render(context.Background(), img); !errors.Is(err, ErrNoGlyphs) {
+				t.Errorf("weight %v, dither %v: got error %v, want ErrNoGlyphs", weight, dither, err)
+			}
+		}
+	}
+}
+
+func TestRemovedWeightsNoLongerApply(t *testing.T) {
+	canvas := New()
+	canvas.SetImage(image.NewRGBA(image.Rect(0, 0, 20, 20)))
+	canvas.AddWeights(map[rune]float64{'#': 0.1})
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	canvas.SetWeights(map[rune]float64{})
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	if weight := canvas.GetWeight('#'); weight != 1 {
+		t.Errorf("GetWeight('#') = %v after clearing weights, want 1", weight)
+	}
+}
+
+func TestRemovedWeightedRunesAreDropped(t *testing.T) {
+	canvas := New()
+	canvas.SetImage(image.NewRGBA(image.Rect(0, 0, 20, 20)))
+	canvas.SetWeights(map[rune]float64{'█': 1})
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := canvas.Font.Glyphs['█']; !ok {
+		t.Fatal("weighted rune outside the rune range was not rasterized")
+	}
+
+	canvas.SetWeights(map[rune]float64{})
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := canvas.Font.Glyphs['█']; ok {
+		t.Error("rune is still a glyph after its weight was removed")
+	}
+	for _, candidate := range canvas.candidates {
+		if candidate.glyph.Unicode == '█' {
+			t.Error("rune is still a candidate after its weight was removed")
+		}
+	}
+}
+
+func TestWithWeightsRejectsNonFinite(t *testing.T) {
+	for _, weight := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := NewWithOptions(WithWeights(map[rune]float64{'#': weight})); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("WithWeights(%v) = %v, want ErrInvalidOption", weight, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package paintbrush
+
+// ProgressEvent reports how many cells of a painting have been rendered.
+type ProgressEvent struct {
+	Done, Total int
+}
+
+// OnProgress sets a callback that is called after every rendered cell with
+// the number of cells rendered so far and the total. The callback runs on the
+// goroutine collecting results, so it should return quickly. A nil callback
+// removes it.
+func (c *Canvas) OnProgress(fn func(done, total int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onProgress = fn
+}
+
+// setProgress records the progress of the current painting and notifies the
+// progress callback and the given observer, if any.
+func (c *Canvas) setProgress(done, total int, observe func(ProgressEvent)) {
+	c.mu.Lock()
+	c.Progress = float32(done) / float32(total)
+	fn := c.onProgress
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn(done, total)
+	}
+	if observe != nil {
+		observe(ProgressEvent{Done: done, Total: total})
+	}
+}
+
+// resetProgress records the progress of a painting that has just started or
+// ended.
+func (c *Canvas) resetProgress(progress float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Progress = progress
+}
+
+// latestProgress returns an observer that sends events to a channel with a
+// buffer of one, replacing the pending event when the receiver falls behind so
+// that painting never blocks on it. It must be the only sender on the channel.
+func latestProgress(events chan ProgressEvent) func(ProgressEvent) {
+	return func(event ProgressEvent) {
+		select {
+		case events <- event:
+		default:
+			select {
+			case <-events:
+			default:
+			}
+			events <- event
+		}
+	}
+}
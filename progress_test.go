@@ -0,0 +1,118 @@
+package paintbrush
+
+import (
+	"image"
+	"sync"
+	"testing"
+)
+
+// progressCanvas returns a canvas painting a small image in several cells.
+func progressCanvas() *Canvas {
+	canvas := New()
+	canvas.SetImage(checkerboard(140))
+	canvas.SetWidth(20)
+	return canvas
+}
+
+func TestOnProgress(t *testing.T) {
+	canvas := progressCanvas()
+	var calls, last, total int
+	canvas.OnProgress(func(done, n int) {
+		calls++
+		if done != last+1 {
+			t.Errorf("progress went from %d to %d", last, done)
+		}
+		last, total = done, n
+	})
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || last != total || calls != total {
+		t.Errorf("got %d calls ending at %d of %d, want one call per cell", calls, last, total)
+	}
+	if progress := canvas.GetProgress(); progress != 1 {
+		t.Errorf("GetProgress() = %v after painting, want 1", progress)
+	}
+
+	canvas.OnProgress(nil)
+	calls = 0
+	if err := canvas.Paint(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("removed callback was called %d times", calls)
+	}
+}
+
+func TestStartPaintingProgress(t *testing.T) {
+	canvas := progressCanvas()
+	var callbacks int
+	canvas.OnProgress(func(done, total int) {
+		callbacks++
+	})
+	painting := canvas.StartPainting()
+
+	// Poll the progress while the painting runs, as a UI would
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var last float32
+		for {
+			progress := canvas.GetProgress()
+			if progress < last {
+				t.Errorf("GetProgress went from %v to %v", last, progress)
+			}
+			last = progress
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	lastDone := 0
+	for event := range painting.Progress() {
+		if event.Done <= lastDone || event.Done > event.Total {
+			t.Errorf("event %+v after %d cells", event, lastDone)
+		}
+		lastDone = event.Done
+	}
+	<-painting.Done()
+	close(stop)
+	wg.Wait()
+
+	result, err := painting.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := result.Width() * result.Height()
+	if lastDone != total {
+		t.Errorf("last event reported %d cells, want %d", lastDone, total)
+	}
+	if callbacks != total {
+		t.Errorf("callback was called %d times, want %d", callbacks, total)
+	}
+	if progress := canvas.GetProgress(); progress != 1 {
+		t.Errorf("GetProgress() = %v after painting, want 1", progress)
+	}
+}
+
+func TestStartPaintingCancel(t *testing.T) {
+	canvas := progressCanvas()
+	canvas.SetImage(image.NewRGBA(image.Rect(0, 0, 700, 700)))
+	canvas.SetWidth(100)
+	painting := canvas.StartPainting()
+	painting.Cancel()
+	for range painting.Progress() {
+	}
+	<-painting.Done()
+	if _, err := painting.Wait(); err == nil {
+		t.Error("cancelled painting finished without an error")
+	}
+	if progress := canvas.GetProgress(); progress != 1 {
+		t.Errorf("GetProgress() = %v after cancelling, want 1", progress)
+	}
+}
@@ -0,0 +1,222 @@
+package paintbrush
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Resample selects how the image is filtered down to the samples each cell is
+// fitted to.
+type Resample int
+
+const (
+	ResampleNearest  Resample = iota // Nearest source pixel to each sample
+	ResampleBox                      // Average of the source area covered by each sample
+	ResampleBilinear                 // Triangle filter
+	ResampleBicubic                  // Catmull-Rom cubic filter
+	ResampleLanczos                  // Three lobe Lanczos filter
+)
+
+// String returns the name of the resampling filter.
+func (r Resample) String() string {
+	switch r {
+	case ResampleNearest:
+		return "nearest"
+	case ResampleBox:
+		return "box"
+	case ResampleBilinear:
+		return "bilinear"
+	case ResampleBicubic:
+		return "bicubic"
+	case ResampleLanczos:
+		return "lanczos"
+	}
+	return fmt.Sprintf("Resample(%d)", int(r))
+}
+
+// MarshalText implements encoding.TextMarshaler, using the name returned by
+// String.
+func (r Resample) MarshalText() ([]byte, error) {
+	return marshalEnum(r, ResampleLanczos)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the names
+// returned by String.
+func (r *Resample) UnmarshalText(text []byte) error {
+	return unmarshalEnum(r, ResampleLanczos, text)
+}
+
+// support returns the radius of the filter, in samples.
+func (r Resample) support() float64 {
+	switch r {
+	case ResampleBilinear:
+		return 1
+	case ResampleBicubic:
+		return 2
+	case ResampleLanczos:
+		return 3
+	}
+	return 0.5
+}
+
+// weight evaluates the filter at a distance from the sample, in samples.
+func (r Resample) weight(t float64) float64 {
+	t = math.Abs(t)
+	switch r {
+	case ResampleBilinear:
+		return math.Max(0, 1-t)
+	case ResampleBicubic:
+		switch {
+		case t < 1:
+			return 1.5*t*t*t - 2.5*t*t + 1
+		case t < 2:
+			return -0.5*t*t*t + 2.5*t*t - 4*t + 2
+		}
+		return 0
+	case ResampleLanczos:
+		if t == 0 {
+			return 1
+		}
+		if t >= 3 {
+			return 0
+		}
+		x := math.Pi * t
+		return 3 * math.Sin(x) * math.Sin(x/3) / (x * x)
+	}
+	return 0
+}
+
+// filterTaps holds the source pixels contributing to one sample along an
+// axis, with their normalized weights.
+type filterTaps struct {
+	index  []int
+	weight []float64
+}
+
+// filterTaps returns the contributions to each of n samples spaced scale
+// source pixels apart, starting at the origin of a source axis of length
+// size. The filter widens when downscaling so that every source pixel is
+// covered, and pixels beyond the edge repeat the edge pixel.
+func (r Resample) filterTaps(n, size int, scale float64) []filterTaps {
+	widen := math.Max(scale, 1)
+	radius := r.support() * widen
+
+	taps := make([]filterTaps, n)
+	for i := range taps {
+		center := (float64(i) + 0.5) * scale
+		var total float64
+		for j := int(math.Floor(center - radius)); float64(j) < center+radius; j++ {
+			var w float64
+			if r == ResampleBox {
+				// Overlap between the source pixel and the sample's area
+				w = math.Min(float64(j+1), center+radius) - math.Max(float64(j), center-radius)
+			} else {
+				w = r.weight((float64(j) + 0.5 - center) / widen)
+			}
+			if w == 0 {
+				continue
+			}
+			taps[i].index = append(taps[i].index, min(max(j, 0), size-1))
+			taps[i].weight = append(taps[i].weight, w)
+			total += w
+		}
+		if total != 0 {
+			for k := range taps[i].weight {
+				taps[i].weight[k] /= total
+			}
+		}
+	}
+	return taps
+}
+
+// resample filters the buffer down to a width by height grid of samples,
+// each covering scaleX by scaleY source pixels from the image's origin.
+// Filtering happens in linear light when requested, and the result is stored
+// gamma-encoded like the source. Rows are filtered by the given number of
+// goroutines.
+func (b *pixelBuffer) resample(width, height int, scaleX, scaleY float64, filter Resample, linear bool, threads int) *pixelBuffer {
+	xTaps := filter.filterTaps(width, b.width, scaleX)
+	yTaps := filter.filterTaps(height, b.height, scaleY)
+
+	// Only the source rows some sample reads need filtering horizontally
+	rows := make([][]Vec4, b.height)
+	var needed []int
+	for _, taps := range yTaps {
+		for _, y := range taps.index {
+			if rows[y] == nil {
+				rows[y] = make([]Vec4, width)
+				needed = append(needed, y)
+			}
+		}
+	}
+	parallelRows(len(needed), threads, func(i int) {
+		y := needed[i]
+		source := make([]Vec4, b.width)
+		for x := range source {
+			source[x] = b.at(x, y)
+			if linear {
+				source[x] = source[x].ToLinear()
+			}
+		}
+		for x, taps := range xTaps {
+			var sum Vec4
+			for k, i := range taps.index {
+				sum = sum.Add(source[i].Mul(taps.weight[k]))
+			}
+			rows[y][x] = sum
+		}
+	})
+
+	scaled := &pixelBuffer{
+		width:  width,
+		height: height,
+		scaleX: scaleX,
+		scaleY: scaleY,
+		pix:    make([]uint16, width*height*4),
+	}
+	parallelRows(height, threads, func(y int) {
+		taps := yTaps[y]
+		for x := 0; x < width; x++ {
+			var sum Vec4
+			for k, i := range taps.index {
+				sum = sum.Add(rows[i][x].Mul(taps.weight[k]))
+			}
+
+			// Cubic and Lanczos filters overshoot, so clamp to a valid
+			// premultiplied color
+			sum.A = math.Max(0, math.Min(1, sum.A))
+			sum.R = math.Max(0, math.Min(sum.A, sum.R))
+			sum.G = math.Max(0, math.Min(sum.A, sum.G))
+			sum.B = math.Max(0, math.Min(sum.A, sum.B))
+			if linear {
+				sum = sum.ToSRGB()
+			}
+
+			i := (y*width + x) * 4
+			scaled.pix[i] = uint16(math.Round(sum.R * 65535))
+			scaled.pix[i+1] = uint16(math.Round(sum.G * 65535))
+			scaled.pix[i+2] = uint16(math.Round(sum.B * 65535))
+			scaled.pix[i+3] = uint16(math.Round(sum.A * 65535))
+		}
+	})
+	return scaled
+}
+
+// parallelRows calls fn for every row below n, spread across threads
+// goroutines.
+func parallelRows(n, threads int, fn func(row int)) {
+	var wg sync.WaitGroup
+	var next atomic.Int64
+	for i := 0; i < max(threads, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := int(next.Add(1)) - 1; row < n; row = int(next.Add(1)) - 1 {
+				fn(row)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -2,80 +2,143 @@ package paintbrush
 
 import (
 	"strings"
-	"sync"
 )
 
-func (c *Canvas) processResults(results []TaskResult, width, height int) {
+// Result is the output of a painting. It owns the grid of cells chosen for
+// the image and produces each output format from it on demand. A Result is
+// never modified once painting has finished, so it can be kept and used from
+// any goroutine while the canvas that painted it goes on to paint other
+// images.
+type Result struct {
+	grid    Grid
+	palette palette // Palette the cell colors were quantized to
 
-	resultIdx := make([][]*TaskResult, height)
-	for i := range resultIdx {
-		resultIdx[i] = make([]*TaskResult, width)
-	}
+	// Glyphs for rendering the grid into an image
+	glyphWidth, glyphHeight int
+	glyphs                  map[rune]Glyph  // Glyphs of the font
+	blocks                  map[rune]*Glyph // Block elements of the render mode, if any
+	gammaCorrect            bool            // Blend glyph edges in linear light
+}
 
-	var wg sync.WaitGroup
-	for i := range results {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			result := &results[i]
-			resultIdx[result.CharY][result.CharX] = result
-		}(i)
+// newResult collects the results of a painting into a grid.
+func (c *Canvas) newResult(results []TaskResult, width, height int) *Result {
+	r := &Result{
+		grid:         newGrid(results, width, height),
+		palette:      c.palette,
+		glyphWidth:   c.Font.GlyphWidth,
+		glyphHeight:  c.Font.GlyphHeight,
+		glyphs:       c.Font.Glyphs,
+		gammaCorrect: c.GammaCorrect,
+	}
+	if c.RenderMode != RenderGlyphs {
+		r.blocks = c.blocks
 	}
-	wg.Wait()
+	return r
+}
 
+// Grid returns a copy of the cells of the result.
+func (r *Result) Grid() Grid {
+	return r.grid.Clone()
+}
+
+// WithGrid returns a result for the given grid, rendered with the same palette
+// and glyphs as this one. Use it to produce the output formats of a grid that
+// has been post-processed. Runes missing from the font are drawn as their
+// background in RGBA output.
+func (r *Result) WithGrid(grid Grid) *Result {
+	clone := *r
+	clone.grid = grid.Clone()
+	return &clone
+}
+
+// Width returns the width of the result in characters.
+func (r *Result) Width() int {
+	return r.grid.Width()
+}
+
+// Height returns the height of the result in characters.
+func (r *Result) Height() int {
+	return r.grid.Height()
+}
+
+// Text returns the result as text with ANSI escape codes.
+func (r *Result) Text() string {
 	var sb strings.Builder
-	lastBg := "\033[0m" // Reset background
-	lastFg := "\033[0m" // Reset foreground
+	r.WriteFormat(&sb, FormatANSI)
+	return sb.String()
+}
 
-	for charY := 0; charY < height; charY++ {
-		for charX := 0; charX < width; charX++ {
-			result := resultIdx[charY][charX]
-			if result == nil {
-				sb.WriteString(" ")
-				continue
-			}
+// C returns the result as a C-style string declaration.
+func (r *Result) C() string {
+	var sb strings.Builder
+	r.WriteFormat(&sb, FormatC)
+	return sb.String()
+}
 
-			var newBg string
-			if result.Bg.A < 0.5 {
-				newBg = "\033[0m"
-			} else {
-				newBg = result.Bg.ToPixel().AnsiBg()
-			}
-			if newBg != lastBg {
-				sb.WriteString(newBg)
-				lastBg = newBg
-			}
+// Bash returns the result as a Bash command printing it.
+func (r *Result) Bash() string {
+	var sb strings.Builder
+	r.WriteFormat(&sb, FormatBash)
+	return sb.String()
+}
 
-			newFg := result.Fg.ToPixel().AnsiFg()
-			if newFg != lastFg {
-				sb.WriteString(newFg)
-				lastFg = newFg
-			}
-			sb.WriteString(result.Glyph.UTF8)
+// RGBABytes renders the result into an image, 4 bytes per pixel. Every cell
+// is drawn as its glyph in the foreground color over the background color.
+func (r *Result) RGBABytes() []byte {
+	width, height := r.RGBADimensions()
+	rgba := make([]byte, width*height*4)
+	for charY, row := range r.grid {
+		for charX, cell := range row {
+			r.blitCell(rgba, width, charX, charY, cell)
 		}
-
-		// Reset colors at the end of each line
-		sb.WriteString("\033[0m\n")
-		lastBg = "\033[0m"
-		lastFg = "\033[0m"
 	}
+	return rgba
+}
 
-	// Remove empty newlines at the end
-	c.Result = sb.String()
-	for strings.HasSuffix(c.Result, "\n") {
-		c.Result = c.Result[:len(c.Result)-1]
-	}
+// RGBADimensions returns the width and height of the rendered image in
+// pixels.
+func (r *Result) RGBADimensions() (width, height int) {
+	return r.Width() * r.glyphWidth, r.Height() * r.glyphHeight
+}
 
-	// Generate C string
-	c.ResultC = strings.ReplaceAll(c.Result, "\033", "\\033")
-	c.ResultC = strings.ReplaceAll(c.ResultC, "\n", "\\n")
-	c.ResultC = strings.ReplaceAll(c.ResultC, "\"", "\\\"")
-	c.ResultC = "char kCanvas[] = \"" + c.ResultC + "\""
+// glyph returns the glyph drawn for a rune, or nil if there is none.
+func (r *Result) glyph(char rune) *Glyph {
+	if glyph, ok := r.blocks[char]; ok {
+		return glyph
+	}
+	if glyph, ok := r.glyphs[char]; ok && len(glyph.Pixels) >= r.glyphWidth*r.glyphHeight {
+		return &glyph
+	}
+	return nil
+}
 
-	// Generate Bash string
-	c.ResultBash = strings.ReplaceAll(c.Result, "\\", "\\\\")
-	c.ResultBash = strings.ReplaceAll(c.ResultBash, "\033", "\\e")
-	c.ResultBash = strings.ReplaceAll(c.ResultBash, "\n", "\\n")
-	c.ResultBash = strings.ReplaceAll(c.ResultBash, "'", "\\x27")
-	c.ResultBash = "echo -ne '" + c.ResultBash + "'"
+// blitCell draws a cell into an image of the given width in pixels.
+func (r *Result) blitCell(rgba []byte, stride, charX, charY int, cell Cell) {
+	glyph := r.glyph(cell.Rune)
+	fg, bg := cell.Fg, cell.Bg
+	if r.gammaCorrect {
+		// Anti-aliased edges blend in linear light, as on a display
+		fg, bg = fg.ToLinear(), bg.ToLinear()
+	}
+	for fontCharY := 0; fontCharY < r.glyphHeight; fontCharY++ {
+		for fontCharX := 0; fontCharX < r.glyphWidth; fontCharX++ {
+			resultX := charX*r.glyphWidth + fontCharX
+			resultY := charY*r.glyphHeight + fontCharY
+			idx := (resultY*stride + resultX) * 4
+			fgFactor := 0.0
+			if glyph != nil {
+				fgFactor = float64(glyph.Pixels[fontCharX+fontCharY*r.glyphWidth]) / 255.0
+			}
+			bgFactor := 1.0 - fgFactor
+			pixel := fg.Mul(fgFactor).Add(bg.Mul(bgFactor))
+			if r.gammaCorrect {
+				pixel = pixel.ToSRGB()
+			}
+			p := pixel.ToPixel()
+			rgba[idx] = p.R
+			rgba[idx+1] = p.G
+			rgba[idx+2] = p.B
+			rgba[idx+3] = p.A
+		}
+	}
 }
@@ -0,0 +1,268 @@
+package paintbrush
+
+import "math"
+
+// CellSample holds the pixels of one output cell, sampled once per glyph
+// pixel. Colors are premultiplied, offset by any dithering bias, and in linear
+// light when gamma correction is enabled.
+type CellSample struct {
+	Width, Height int    // Glyph dimensions the cell was sampled at
+	Pixels        []Vec4 // Samples in row-major order
+
+	canvas *Canvas
+
+	// Sums over the cell shared by every glyph fitted to it
+	sum    Vec4    // Σ col
+	sumP   Vec4    // Σ p, where p = col·col.A is the color the error compares
+	sumPP  float64 // Σ p·p
+	opaque bool    // Every sample is opaque, so p equals col
+
+	premul []Vec4 // p for every sample, when the cell is not opaque
+	target []Vec4 // p converted into the space of the color metric
+
+	structure *cellStructure // Computed by StructuralScorer on first use
+}
+
+// Quantize snaps a foreground color to the palette of the canvas being
+// painted.
+func (s *CellSample) Quantize(v Vec4) Vec4 {
+	return s.canvas.quantize(v)
+}
+
+// QuantizeBackground snaps a background color to the palette of the canvas
+// being painted. In monochrome the terminal's default background is kept,
+// which is transparent.
+func (s *CellSample) QuantizeBackground(v Vec4) Vec4 {
+	return s.canvas.quantizeBg(v)
+}
+
+// Distance returns the difference between two colors under the color metric
+// of the canvas being painted.
+func (s *CellSample) Distance(a, b Vec4) float64 {
+	return s.canvas.distance(a, b)
+}
+
+// GlyphScorer decides how well a glyph renders a cell. Implementations must be
+// safe for concurrent use, as every worker shares the same scorer.
+type GlyphScorer interface {
+	// Score returns the foreground and background colors that best render the
+	// cell with the glyph, along with the error of the result. Lower errors
+	// are better.
+	Score(glyph *Glyph, cell *CellSample) (fg, bg Vec4, err float64)
+}
+
+// sampleCell reads the pixels of a cell at the glyph resolution, along with
+// the sums over them that ColorScorer needs.
+func (c *Canvas) sampleCell(task Task, imgCharWidth, imgCharHeight float64) *CellSample {
+	imgXBegin := float64(task.CharX) * imgCharWidth
+	imgYBegin := float64(task.CharY) * imgCharHeight
+
+	cell := &CellSample{
+		Width:  c.Font.GlyphWidth,
+		Height: c.Font.GlyphHeight,
+		Pixels: make([]Vec4, c.Font.GlyphWidth*c.Font.GlyphHeight),
+		canvas: c,
+		opaque: true,
+	}
+	for fontCharY := 0; fontCharY < c.Font.GlyphHeight; fontCharY++ {
+		for fontCharX := 0; fontCharX < c.Font.GlyphWidth; fontCharX++ {
+			imgX := imgXBegin + imgCharWidth*(float64(fontCharX)+0.5)/float64(c.Font.GlyphWidth)
+			imgY := imgYBegin + imgCharHeight*(float64(fontCharY)+0.5)/float64(c.Font.GlyphHeight)
+			col := c.sampleImage(imgX, imgY, task.Bias)
+			cell.Pixels[fontCharX+fontCharY*c.Font.GlyphWidth] = col
+			cell.opaque = cell.opaque && col.A == 1
+		}
+	}
+
+	if !cell.opaque {
+		cell.premul = make([]Vec4, len(cell.Pixels))
+	}
+	if !c.euclideanFit() {
+		cell.target = make([]Vec4, len(cell.Pixels))
+	}
+	for i, col := range cell.Pixels {
+		p := col.Mul(col.A)
+		cell.sum = cell.sum.Add(col)
+		cell.sumP = cell.sumP.Add(p)
+		cell.sumPP += p.Dot(p)
+		if cell.premul != nil {
+			cell.premul[i] = p
+		}
+		if cell.target != nil {
+			cell.target[i] = c.metricSpace(p)
+		}
+	}
+	return cell
+}
+
+// ColorScorer is the default scorer. The cell is averaged under the glyph's
+// coverage into a foreground and a background color, which are quantized to
+// the palette, and the error is the summed color difference between every
+// pixel of the cell and the glyph as rendered.
+//
+// With a coverage f per pixel, the error under a plain squared distance
+// expands to Σp² - 2fg·Σfp - 2bg·Σ(1-f)p + fg²Σf² + 2fg·bgΣf(1-f) + bg²Σ(1-f)²,
+// so the only per-glyph work is the weighted sum Σfp over the glyph's ink.
+// Other metrics compare every pixel individually.
+type ColorScorer struct{}
+
+// Score implements GlyphScorer.
+func (ColorScorer) Score(glyph *Glyph, cell *CellSample) (fg, bg Vec4, err float64) {
+	fit := glyph.fitData(cell.Width)
+
+	// Σ f·col, from which both means follow. Pixels without coverage add
+	// nothing, so only the glyph's ink is visited
+	var fgCol Vec4
+	for _, i := range fit.ink {
+		fgCol = fgCol.Add(cell.Pixels[i].Mul(fit.coverage[i]))
+	}
+
+	fg = fgCol
+	if fit.sumF > 0 {
+		fg = fg.Div(fit.sumF)
+	}
+	fg.A = 1
+
+	bg = cell.sum.Sub(fgCol)
+	if fit.sumB > 0 {
+		bg = bg.Div(fit.sumB)
+	}
+	if bg.A < 0.2 {
+		bg.A = 0
+	} else {
+		bg.A = 1
+	}
+	bg = bg.Mul(bg.A) // premultiply
+
+	fg = cell.Quantize(fg)
+	bg = cell.QuantizeBackground(bg)
+
+	if cell.target != nil {
+		for i, target := range cell.target {
+			f := fit.coverage[i]
+			x := cell.canvas.metricSpace(fg.Mul(f).Add(bg.Mul(1 - f)))
+			err += cell.canvas.Metric.spaceDistance(target, x)
+		}
+		return fg, bg, err
+	}
+
+	fgP := fgCol
+	if !cell.opaque {
+		fgP = Vec4{}
+		for _, i := range fit.ink {
+			fgP = fgP.Add(cell.premul[i].Mul(fit.coverage[i]))
+		}
+	}
+	bgP := cell.sumP.Sub(fgP)
+
+	// The expansion cancels to rounding noise when the glyph fits the cell
+	// exactly, which must not go below the true minimum of zero
+	err = cell.sumPP - 2*fg.Dot(fgP) - 2*bg.Dot(bgP) +
+		fg.Dot(fg)*fit.sumFF + 2*fg.Dot(bg)*fit.sumFB + bg.Dot(bg)*fit.sumBB
+	return fg, bg, math.Max(0, err)
+}
+
+// StructuralScorer adds a penalty to the color error of glyphs whose edges
+// run in other directions than the edges of the cell. Edge directions are
+// compared through the dominant gradient orientation of the cell's luminance
+// and of the glyph's coverage, so lines and contours of the image map onto
+// glyphs such as /, |, - and \ even where they are not aligned with the
+// glyph's strokes, which the color error alone punishes.
+//
+// The penalty is relative to the contrast of the cell, measured as the error
+// of rendering it in its mean color, so flat cells are scored by color alone.
+type StructuralScorer struct {
+	// Structure scales the penalty of a glyph whose edges run across those
+	// of the cell, which is Structure times the contrast of the cell. Zero
+	// reduces the scorer to plain color error.
+	Structure float64
+}
+
+// Score implements GlyphScorer.
+func (s StructuralScorer) Score(glyph *Glyph, cell *CellSample) (fg, bg Vec4, err float64) {
+	fg, bg, err = ColorScorer{}.Score(glyph, cell)
+	if s.Structure == 0 {
+		return fg, bg, err
+	}
+
+	structure := cell.structureData()
+	if structure.contrast == 0 {
+		return fg, bg, err
+	}
+	similarity := structure.orientation.similarity(glyph.fitData(cell.Width).shape)
+	return fg, bg, err + s.Structure*structure.contrast*(1-similarity)/2
+}
+
+// cellStructure holds what StructuralScorer compares every glyph with.
+type cellStructure struct {
+	orientation orientation // Of the cell's luminance
+	contrast    float64     // Error of rendering the cell in its mean color
+}
+
+// structureData returns the structure of the cell, computing it on first use.
+// A cell is only ever scored by one worker, so no locking is needed.
+func (s *CellSample) structureData() *cellStructure {
+	if s.structure != nil {
+		return s.structure
+	}
+
+	n := float64(len(s.Pixels))
+	mean := s.sumP.Div(n)
+	structure := &cellStructure{
+		orientation: gradientOrientation(s.Width, s.Height, func(i int) float64 {
+			col := s.Pixels[i]
+			return luminance(col.Mul(col.A))
+		}),
+	}
+	for _, col := range s.Pixels {
+		structure.contrast += s.Distance(col.Mul(col.A), mean)
+	}
+	s.structure = structure
+	return structure
+}
+
+// orientation is the sum of the gradients of an image in the doubled-angle
+// representation, in which gradients of opposite sign, such as those on
+// either side of a line, add up rather than cancel.
+type orientation struct {
+	x, y   float64 // Σ (gx² - gy², 2·gx·gy)
+	energy float64 // Σ (gx² + gy²), the largest length of (x, y)
+}
+
+// gradientOrientation returns the orientation of a width by height image
+// whose values are given by value, using Sobel gradients with the edge
+// pixels repeated beyond the image.
+func gradientOrientation(width, height int, value func(i int) float64) orientation {
+	at := func(x, y int) float64 {
+		x = max(0, min(width-1, x))
+		y = max(0, min(height-1, y))
+		return value(x + y*width)
+	}
+
+	var o orientation
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			o.x += gx*gx - gy*gy
+			o.y += 2 * gx * gy
+			o.energy += gx*gx + gy*gy
+		}
+	}
+	return o
+}
+
+// similarity returns 1 when two orientations run in the same direction with
+// all their gradients, -1 when they run across each other, and values in
+// between when their gradients are spread over several directions. An image
+// without edges is only similar to another one.
+func (o orientation) similarity(other orientation) float64 {
+	const epsilon = 1e-12
+	switch {
+	case o.energy < epsilon && other.energy < epsilon:
+		return 1
+	case o.energy < epsilon || other.energy < epsilon:
+		return 0
+	}
+	return (o.x*other.x + o.y*other.y) / (o.energy * other.energy)
+}
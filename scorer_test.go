@@ -0,0 +1,221 @@
+package paintbrush
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png"
+	"math"
+	"os"
+	"testing"
+)
+
+// perPixelScorer scores glyphs the way ColorScorer did before the closed form,
+// comparing every pixel of the cell with the glyph as rendered.
+type perPixelScorer struct{}
+
+func (perPixelScorer) Score(glyph *Glyph, cell *CellSample) (fg, bg Vec4, err float64) {
+	fgSum, bgSum := 0.0, 0.0
+	for i, col := range cell.Pixels {
+		f := float64(glyph.Pixels[i]) / 255.0
+		fg = fg.Add(col.Mul(f))
+		bg = bg.Add(col.Mul(1 - f))
+		fgSum += f
+		bgSum += 1 - f
+	}
+
+	if fgSum > 0 {
+		fg = fg.Div(fgSum)
+	}
+	fg.A = 1
+
+	if bgSum > 0 {
+		bg = bg.Div(bgSum)
+	}
+	if bg.A < 0.2 {
+		bg.A = 0
+	} else {
+		bg.A = 1
+	}
+	bg = bg.Mul(bg.A)
+
+	fg = cell.Quantize(fg)
+	bg = cell.QuantizeBackground(bg)
+
+	for i, col := range cell.Pixels {
+		f := float64(glyph.Pixels[i]) / 255.0
+		err += cell.Distance(col.Mul(col.A), fg.Mul(f).Add(bg.Mul(1-f)))
+	}
+	return fg, bg, err
+}
+
+// loadNorman decodes the example image.
+func loadNorman(tb testing.TB) image.Image {
+	tb.Helper()
+	file, err := os.Open("examples/norman.png")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return img
+}
+
+func TestColorScorerMatchesPerPixelScorer(t *testing.T) {
+	img := loadNorman(t)
+	// The per-pixel scorer converts every pixel out of linear light, so the
+	// gamma-correct render is kept smaller
+	for _, tt := range []struct {
+		width int
+		gamma bool
+	}{{150, false}, {40, true}} {
+		gamma := tt.gamma
+		canvas := New()
+		canvas.SetWidth(tt.width)
+		canvas.SetGammaCorrect(gamma)
+
+		want, err := canvas.Render(context.Background(), img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		canvas.SetScorer(perPixelScorer{})
+		got, err := canvas.Render(context.Background(), img)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantGrid, gotGrid := want.Grid(), got.Grid()
+		if wantGrid.Height() != gotGrid.Height() || wantGrid.Width() != gotGrid.Width() {
+			t.Fatalf("gamma %v: grid is %dx%d, want %dx%d", gamma, gotGrid.Width(), gotGrid.Height(), wantGrid.Width(), wantGrid.Height())
+		}
+		for y, row := range wantGrid {
+			for x, cell := range row {
+				other := gotGrid[y][x]
+				if cell.Rune != other.Rune {
+					t.Errorf("gamma %v: cell %d,%d is %q with the per-pixel scorer, %q with ColorScorer", gamma, x, y, other.Rune, cell.Rune)
+					continue
+				}
+				if !closeColor(cell.Fg, other.Fg) || !closeColor(cell.Bg, other.Bg) {
+					t.Errorf("gamma %v: cell %d,%d colors differ: %v %v, want %v %v", gamma, x, y, other.Fg, other.Bg, cell.Fg, cell.Bg)
+				}
+			}
+		}
+	}
+}
+
+func TestFlatCellsPickLowestRune(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 700, 700))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 200, 40, 60, 255
+	}
+	canvas := New()
+	canvas.SetWidth(10)
+	result, err := canvas.Render(context.Background(), img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y, row := range result.Grid() {
+		for x, cell := range row {
+			if cell.Rune != ' ' {
+				t.Fatalf("flat cell %d,%d is %q, want ' '", x, y, cell.Rune)
+			}
+		}
+	}
+}
+
+// closeColor reports whether two colors are equal up to rounding.
+func closeColor(a, b Vec4) bool {
+	const epsilon = 1e-9
+	return math.Abs(a.R-b.R) < epsilon && math.Abs(a.G-b.G) < epsilon &&
+		math.Abs(a.B-b.B) < epsilon && math.Abs(a.A-b.A) < epsilon
+}
+
+func benchmarkScorer(b *testing.B, scorer GlyphScorer, runeLimit int) {
+	img := loadNorman(b)
+	canvas := New()
+	canvas.SetWidth(150)
+	canvas.SetRuneLimits(32, runeLimit)
+	canvas.SetScorer(scorer)
+	if err := canvas.ensureFont(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := canvas.Render(context.Background(), img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The default rune range, 32 up to 95, holds 63 glyphs. The scorers only
+// differ in the work done per glyph, so the gap widens with larger character
+// sets such as the 352 glyphs up to the end of Latin Extended-A.
+
+func BenchmarkColorScorer(b *testing.B) {
+	benchmarkScorer(b, ColorScorer{}, 95)
+}
+
+func BenchmarkPerPixelScorer(b *testing.B) {
+	benchmarkScorer(b, perPixelScorer{}, 95)
+}
+
+func BenchmarkColorScorerLatinExtended(b *testing.B) {
+	benchmarkScorer(b, ColorScorer{}, 0x180)
+}
+
+func BenchmarkPerPixelScorerLatinExtended(b *testing.B) {
+	benchmarkScorer(b, perPixelScorer{}, 0x180)
+}
+
+// lineCell returns an image of three cells of 14 by 28 pixels, twice the
+// default glyph size, white with a black line two pixels wide through the
+// middle cell, offset from its center. The line is vertical, or diagonal
+// across the cell when slope is 1 or -1.
+func lineCell(slope, offset int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 3*14, 28))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 0; y < 28; y++ {
+		x := 14 + 6 + offset - slope*(y-14)/2
+		img.Set(x, y, color.Black)
+		img.Set(x+1, y, color.Black)
+	}
+	return img
+}
+
+func TestStructuralScorerFollowsLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		slope, offset int
+		want          rune
+	}{
+		{"centered falling diagonal", -1, 0, '\\'},
+		{"rising diagonal left of center", 1, -2, '/'},
+		{"rising diagonal right of center", 1, 2, '/'},
+		{"vertical line right of center", 0, 4, '|'},
+	}
+	render := func(scorer GlyphScorer, img image.Image) rune {
+		canvas := New()
+		canvas.SetRuneLimits(32, 127)
+		canvas.SetWidth(3)
+		canvas.SetHeight(1)
+		canvas.SetScorer(scorer)
+		result, err := canvas.Render(context.Background(), img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Grid()[0][1].Rune
+	}
+	for _, tt := range tests {
+		img := lineCell(tt.slope, tt.offset)
+		if got := render(ColorScorer{}, img); got == tt.want {
+			t.Errorf("%s: ColorScorer already picks %q", tt.name, got)
+		}
+		if got := render(StructuralScorer{Structure: 2}, img); got != tt.want {
+			t.Errorf("%s: StructuralScorer picks %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package paintbrush
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SVGOptions configures the SVG output of a result.
+type SVGOptions struct {
+	FontFamily string // Font family of the text, monospace when empty
+}
+
+// SVG returns the result as an SVG document.
+func (r *Result) SVG(opts SVGOptions) string {
+	var sb strings.Builder
+	r.WriteSVG(&sb, opts)
+	return sb.String()
+}
+
+// WriteSVG writes the result to w as an SVG document, one row at a time. Every
+// cell is GlyphWidth by GlyphHeight units, like in the RGBA output, with its
+// background drawn as a rectangle and its rune as text of the glyph height,
+// centered in the cell on the baseline glyphs are rasterized with. Adjacent
+// cells of the same colors share one <rect> or <tspan>. Colors are those of
+// the color mode the result was painted with, and monochrome results are
+// written without colors.
+func (r *Result) WriteSVG(w io.Writer, opts SVGOptions) (int64, error) {
+	font := opts.FontFamily
+	if font == "" {
+		font = "monospace"
+	}
+	width, height := r.RGBADimensions()
+
+	cw := &countingWriter{w: w}
+	fmt.Fprintf(cw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+
+	var buf []byte
+	io.WriteString(cw, `<g shape-rendering="crispEdges">`+"\n")
+	for y := range r.grid {
+		buf = r.appendSVGRects(buf[:0], y)
+		cw.Write(buf)
+		if cw.err != nil {
+			return cw.n, cw.err
+		}
+	}
+	io.WriteString(cw, "</g>\n")
+
+	fmt.Fprintf(cw, `<g font-family="%s" font-size="%d" text-anchor="middle" xml:space="preserve">`+"\n",
+		html.EscapeString(font), r.glyphHeight)
+	for y := range r.grid {
+		buf = r.appendSVGText(buf[:0], y)
+		cw.Write(buf)
+		if cw.err != nil {
+			return cw.n, cw.err
+		}
+	}
+	io.WriteString(cw, "</g>\n</svg>")
+	return cw.n, cw.err
+}
+
+// appendSVGRects appends the backgrounds of a row as rectangles, one for
+// every run of cells with the same color.
+func (r *Result) appendSVGRects(buf []byte, y int) []byte {
+	row := r.grid[y]
+	for start := 0; start < len(row); {
+		_, bg := r.cssColors(row[start])
+		end := start + 1
+		for end < len(row) {
+			if _, next := r.cssColors(row[end]); next != bg {
+				break
+			}
+			end++
+		}
+		if bg != "" {
+			buf = fmt.Appendf(buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%s"/>`+"\n",
+				start*r.glyphWidth, y*r.glyphHeight, (end-start)*r.glyphWidth, r.glyphHeight, bg)
+		}
+		start = end
+	}
+	return buf
+}
+
+// appendSVGText appends a row as a <text> element, with a <tspan> for every
+// run of cells with the same foreground color. Every character is positioned
+// at the center of its cell.
+func (r *Result) appendSVGText(buf []byte, y int) []byte {
+	row := r.grid[y]
+	if len(row) == 0 {
+		return buf
+	}
+
+	// Glyphs are rasterized with their baseline at four fifths of the cell
+	baseline := y*r.glyphHeight + r.glyphHeight*4/5
+	buf = fmt.Appendf(buf, `<text y="%d">`, baseline)
+	for start := 0; start < len(row); {
+		fg, _ := r.cssColors(row[start])
+		end := start + 1
+		for end < len(row) {
+			if next, _ := r.cssColors(row[end]); next != fg {
+				break
+			}
+			end++
+		}
+
+		buf = append(buf, `<tspan x="`...)
+		for x := start; x < end; x++ {
+			if x > start {
+				buf = append(buf, ' ')
+			}
+			center := float64(x*r.glyphWidth) + float64(r.glyphWidth)/2
+			buf = strconv.AppendFloat(buf, center, 'f', -1, 64)
+		}
+		buf = append(buf, '"')
+		if fg != "" {
+			buf = append(buf, ` fill="#`+fg+`"`...)
+		}
+		buf = append(buf, '>')
+		for _, cell := range row[start:end] {
+			buf = appendEscapedRune(buf, cell.Rune)
+		}
+		buf = append(buf, "</tspan>"...)
+		start = end
+	}
+	return append(buf, "</text>\n"...)
+}
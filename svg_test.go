@@ -0,0 +1,67 @@
+package paintbrush
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name string
+		mode ColorMode
+		opts SVGOptions
+		want string
+	}{
+		{
+			"colors", ColorModeTrueColor, SVGOptions{FontFamily: `"Fira Mono"`},
+			`<svg xmlns="http://www.w3.org/2000/svg" width="28" height="28" viewBox="0 0 28 28">` + "\n" +
+				`<g shape-rendering="crispEdges">` + "\n" +
+				`<rect x="0" y="0" width="21" height="14" fill="#0000ff"/>` + "\n" +
+				"</g>\n" +
+				`<g font-family="&#34;Fira Mono&#34;" font-size="14" text-anchor="middle" xml:space="preserve">` + "\n" +
+				`<text y="11"><tspan x="3.5 10.5" fill="#ff0000">ab</tspan><tspan x="17.5 24.5" fill="#00ff00">&lt;&amp;</tspan></text>` + "\n" +
+				`<text y="25"><tspan x="3.5 10.5" fill="#ff0000">&gt; </tspan></text>` + "\n" +
+				"</g>\n</svg>",
+		},
+		{
+			"monochrome", ColorModeMonochrome, SVGOptions{},
+			`<svg xmlns="http://www.w3.org/2000/svg" width="28" height="28" viewBox="0 0 28 28">` + "\n" +
+				`<g shape-rendering="crispEdges">` + "\n" +
+				"</g>\n" +
+				`<g font-family="monospace" font-size="14" text-anchor="middle" xml:space="preserve">` + "\n" +
+				`<text y="11"><tspan x="3.5 10.5 17.5 24.5">ab&lt;&amp;</tspan></text>` + "\n" +
+				`<text y="25"><tspan x="3.5 10.5">&gt; </tspan></text>` + "\n" +
+				"</g>\n</svg>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			n, err := gridResult(t, tt.mode, testGrid).WriteSVG(&sb, tt.opts)
+			if err != nil || n != int64(sb.Len()) {
+				t.Errorf("WriteSVG = %d, %v after writing %d bytes", n, err, sb.Len())
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSVGMergesBackgroundRuns(t *testing.T) {
+	grid := Grid{{{'a', red, blue}, {'b', green, blue}, {'c', green, red}, {'d', green, Vec4{}}, {'e', green, red}}}
+	svg := gridResult(t, ColorModeTrueColor, grid).SVG(SVGOptions{})
+	for _, want := range []string{
+		`<rect x="0" y="0" width="14" height="14" fill="#0000ff"/>`,
+		`<rect x="14" y="0" width="7" height="14" fill="#ff0000"/>`,
+		`<rect x="28" y="0" width="7" height="14" fill="#ff0000"/>`,
+		`<tspan x="3.5" fill="#ff0000">a</tspan><tspan x="10.5 17.5 24.5 31.5" fill="#00ff00">bcde</tspan>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG does not contain %s:\n%s", want, svg)
+		}
+	}
+	if got := strings.Count(svg, "<rect"); got != 3 {
+		t.Errorf("SVG has %d rects, want 3:\n%s", got, svg)
+	}
+}